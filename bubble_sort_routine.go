@@ -18,48 +18,42 @@ You should have received a copy of the GNU Affero General Public License
 along with this program.  If not, see <https://www.gnu.org/licenses/>.
 */
 
+import "context"
+
 // BubbleSortRoutine - sorts by moving small items to the head of the list iteratively
 type BubbleSortRoutine struct {
-	data                 []int32
-	dataSize             int32
-	swapChannel          chan SwapEvent
-	comparisonChannel    chan ComparisonEvent
-	knownToBeSortedCount int32
+	sortRoutineBase
 }
 
 // NewBubbleSortRoutine factory
 func NewBubbleSortRoutine(startSlice []int32) *BubbleSortRoutine {
 	bsr := new(BubbleSortRoutine)
-	bsr.dataSize = int32(len(startSlice))
-	bsr.data = make([]int32, bsr.dataSize)
-	_ = copy(bsr.data, startSlice)
-	cc := make(chan ComparisonEvent, 1000)
-	bsr.comparisonChannel = cc
-	sc := make(chan SwapEvent, 1000)
-	bsr.swapChannel = sc
-	bsr.knownToBeSortedCount = 0
+	bsr.sortRoutineBase = newSortRoutineBase(startSlice)
 	return bsr
 }
 
-func (bsr BubbleSortRoutine) getComparisonChannel() chan ComparisonEvent {
-	return bsr.comparisonChannel
-}
-
-func (bsr BubbleSortRoutine) getSwapChannel() chan SwapEvent {
-	return bsr.swapChannel
+func init() {
+	registerSortAlgorithm(ALGORITHM_BUBBLE_SORT, registeredSortAlgorithm{
+		factory:           func(startSlice []int32) SortRoutine { return NewBubbleSortRoutine(startSlice) },
+		includedByDefault: true,
+		sortedRegion:      SORTED_REGION_HEAD,
+	})
 }
 
-func (bsr BubbleSortRoutine) run() {
+func (bsr BubbleSortRoutine) run(ctx context.Context) {
 	var top int32 = int32(0)
 	var bottom int32 = int32(len(bsr.data) - 1)
 	for top = int32(0); top < bottom; top = top + 1 {
+		if ctx.Err() != nil {
+			return
+		}
 		var pos int32
 		for pos = bottom - 1; pos >= top; pos = pos - 1 {
-			if !compareElementsAt(bsr.data, pos, pos+1, bsr.knownToBeSortedCount, bsr.comparisonChannel) {
-				swapElementsAt(bsr.data, pos, pos+1, bsr.knownToBeSortedCount, bsr.swapChannel)
+			if !bsr.compareElementsAt(pos, pos+1) {
+				bsr.swapElementsAt(pos, pos+1)
 			}
 		}
 		bsr.knownToBeSortedCount = top
 	}
-	sortingRoutineComplete(bsr.comparisonChannel, bsr.swapChannel)
+	bsr.sortingRoutineComplete()
 }
@@ -1,6 +1,10 @@
 package main
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+	"io"
+)
 
 /*
 Parallel Sorting Demo
@@ -26,6 +30,8 @@ type ComparisonEvent struct {
 	value                [2]int32 // the values of compared elements
 	firstWasLower        bool     // the result of the comparison (true if first element is "less than" second)
 	knownToBeSortedCount int32    // the count of elements currently known to be sorted
+	sequence             int64    // the position of the event among all events of the routine, starting at 1
+	timestamp            int64    // when the event occurred, in nanoseconds since the Unix epoch
 }
 
 // SwapEvent represents an occurrence of swapping two elements
@@ -33,6 +39,18 @@ type SwapEvent struct {
 	index                [2]int32 // the indexes of compared elements
 	value                [2]int32 // the values of compared elements
 	knownToBeSortedCount int32    // the count of elements currently known to be sorted
+	sequence             int64    // the position of the event among all events of the routine, starting at 1
+	timestamp            int64    // when the event occurred, in nanoseconds since the Unix epoch
+}
+
+// WriteEvent represents an occurrence of copying one element between the data array and an auxiliary buffer
+type WriteEvent struct {
+	index                [2]int32 // the indexes of the destination and source elements
+	value                [2]int32 // the value overwritten at the destination and the value written from the source
+	toAuxiliary          bool     // the direction of the copy (true if the destination is the auxiliary buffer, false if it is the data array)
+	knownToBeSortedCount int32    // the count of elements currently known to be sorted
+	sequence             int64    // the position of the event among all events of the routine, starting at 1
+	timestamp            int64    // when the event occurred, in nanoseconds since the Unix epoch
 }
 
 var sortingCompleteComparisonEvent = ComparisonEvent{
@@ -43,88 +61,175 @@ var sortingCompleteSwapEvent = SwapEvent{
 	knownToBeSortedCount: SORTING_COMPLETE_VALUE,
 }
 
-func startSupervisionOfSort(cm chan int, sm chan int, algorithm int) {
-	cm <- algorithm
-	sm <- algorithm
+var sortingCompleteWriteEvent = WriteEvent{
+	knownToBeSortedCount: SORTING_COMPLETE_VALUE,
+}
+
+var sortingAbandonedComparisonEvent = ComparisonEvent{
+	knownToBeSortedCount: SORTING_ABANDONED_VALUE,
+}
+
+var sortingAbandonedSwapEvent = SwapEvent{
+	knownToBeSortedCount: SORTING_ABANDONED_VALUE,
+}
+
+var sortingAbandonedWriteEvent = WriteEvent{
+	knownToBeSortedCount: SORTING_ABANDONED_VALUE,
+}
+
+// progressSettings controls how often and where progress is reported
+type progressSettings struct {
+	reportPeriodStep float32
+	output           io.Writer
+}
+
+// progressReporter prints the progress of one algorithm's event stream each time another reportPeriodStep of the data is known to be sorted
+type progressReporter struct {
+	algorithm        int
+	dataSize         int32
+	eventDescription string
+	settings         progressSettings
+	nextReportAt     float32
+	eventCount       int64
+}
+
+func newProgressReporter(result *algorithmResult, eventDescription string, settings progressSettings) *progressReporter {
+	return &progressReporter{result.algorithm, result.dataSize, eventDescription, settings, 0, 0}
+}
+
+func proportionSorted(knownToBeSortedCount int32, dataSize int32) float32 {
+	if knownToBeSortedCount == SORTING_COMPLETE_VALUE || dataSize == 0 {
+		return 1.0
+	}
+	if knownToBeSortedCount < 0 {
+		return 0.0
+	}
+	return float32(knownToBeSortedCount) / float32(dataSize)
 }
 
-func monitorSupervisorChannel(m chan int, msc chan string, completeMessage string) {
-	var alg int
-	var runningAlgorithms = map[int]bool{}
-	// always begin with starting the first algorithm
-	alg = <-m
-	if alg > 0 {
-		runningAlgorithms[alg] = true
+// record counts an event (not counting the final event signalling completion) and reports progress if another reporting step has been reached
+func (pr *progressReporter) record(knownToBeSortedCount int32) {
+	if knownToBeSortedCount == SORTING_ABANDONED_VALUE {
+		fmt.Fprintf(pr.settings.output, "algorithm %s gave up with %d %s\n", algorithmName[pr.algorithm], pr.eventCount, pr.eventDescription)
+		return
 	}
-	for len(runningAlgorithms) > 0 {
-		alg = <-m
-		if alg > 0 {
-			runningAlgorithms[alg] = true
-		} else if alg < 0 {
-			delete(runningAlgorithms, -alg)
+	if knownToBeSortedCount != SORTING_COMPLETE_VALUE {
+		pr.eventCount = pr.eventCount + 1
+	}
+	var proportion float32 = proportionSorted(knownToBeSortedCount, pr.dataSize)
+	if proportion >= pr.nextReportAt {
+		fmt.Fprintf(pr.settings.output, "algorithm %s at %.0f%% with %d %s\n", algorithmName[pr.algorithm], proportion*100, pr.eventCount, pr.eventDescription)
+		for pr.nextReportAt <= proportion {
+			pr.nextReportAt = pr.nextReportAt + pr.settings.reportPeriodStep
 		}
 	}
-	// no more algorithms are running
-	msc <- completeMessage
 }
 
-func processComparisonChannel(c chan ComparisonEvent, algorithm int, m chan int) {
-	nextReportAt := make([]float32, 100)
-	compareCount := make([]int64, 100)
-	const reportPeriodStep = 0.2
+// the receive functions wait for the next event, returning false if ctx is done and no event is waiting,
+// so that channel processing finishes once a routine has been cancelled or has returned without signalling completion
+
+func receiveComparison(ctx context.Context, c chan ComparisonEvent, ce *ComparisonEvent) bool {
+	select {
+	case *ce = <-c:
+		return true
+	case <-ctx.Done():
+	}
+	select {
+	case *ce = <-c:
+		return true
+	default:
+		return false
+	}
+}
+
+func receiveSwap(ctx context.Context, c chan SwapEvent, se *SwapEvent) bool {
+	select {
+	case *se = <-c:
+		return true
+	case <-ctx.Done():
+	}
+	select {
+	case *se = <-c:
+		return true
+	default:
+		return false
+	}
+}
+
+func receiveWrite(ctx context.Context, c chan WriteEvent, we *WriteEvent) bool {
+	select {
+	case *we = <-c:
+		return true
+	case <-ctx.Done():
+	}
+	select {
+	case *we = <-c:
+		return true
+	default:
+		return false
+	}
+}
+
+func processComparisonChannel(ctx context.Context, c chan ComparisonEvent, result *algorithmResult, settings progressSettings, observer eventObserver, done func()) {
+	var pr *progressReporter = newProgressReporter(result, "comparisons", settings)
 	var ce ComparisonEvent
 	for true {
-		ce = <-c
-		compareCount[algorithm] = compareCount[algorithm] + 1
-		proportionSorted := float32(ce.knownToBeSortedCount) / 1000
-		if ce.knownToBeSortedCount == SORTING_COMPLETE_VALUE {
-			proportionSorted = 1.0
+		if !receiveComparison(ctx, c, &ce) {
+			result.comparisons = pr.eventCount
+			done()
+			return
 		}
-		if proportionSorted >= nextReportAt[algorithm] {
-			fmt.Printf("algorithm %s at %.0f%% with %d comparisons\n", algorithmName[algorithm], proportionSorted*100, compareCount[algorithm])
-			nextReportAt[algorithm] = nextReportAt[algorithm] + reportPeriodStep
+		pr.record(ce.knownToBeSortedCount)
+		if ce.knownToBeSortedCount >= 0 {
+			observer.observeComparison(result.algorithm, ce)
 		}
-		if ce == sortingCompleteComparisonEvent {
-			m <- -algorithm // signal that this channel processing is done
+		if ce == sortingCompleteComparisonEvent || ce == sortingAbandonedComparisonEvent {
+			result.comparisons = pr.eventCount
+			result.gaveUp = ce == sortingAbandonedComparisonEvent
+			done()
 			return
 		}
 	}
 }
 
-func processSwapChannel(c chan SwapEvent, algorithm int, m chan int) {
-	nextReportAt := make([]float32, 100)
-	swapCount := make([]int64, 100)
-	const reportPeriodStep = 0.2
+func processSwapChannel(ctx context.Context, c chan SwapEvent, result *algorithmResult, settings progressSettings, observer eventObserver, done func()) {
+	var pr *progressReporter = newProgressReporter(result, "swaps", settings)
 	var se SwapEvent
 	for true {
-		se = <-c
-		swapCount[algorithm] = swapCount[algorithm] + 1
-		proportionSorted := float32(se.knownToBeSortedCount) / 1000
-		if se.knownToBeSortedCount == SORTING_COMPLETE_VALUE {
-			proportionSorted = 1.0
+		if !receiveSwap(ctx, c, &se) {
+			result.swaps = pr.eventCount
+			done()
+			return
 		}
-		if proportionSorted >= nextReportAt[algorithm] {
-			fmt.Printf("algorithm %s at %.0f%% with %d swaps\n", algorithmName[algorithm], proportionSorted*100, swapCount[algorithm])
-			nextReportAt[algorithm] = nextReportAt[algorithm] + reportPeriodStep
+		pr.record(se.knownToBeSortedCount)
+		if se.knownToBeSortedCount >= 0 {
+			observer.observeSwap(result.algorithm, se)
 		}
-		if se == sortingCompleteSwapEvent {
-			m <- -algorithm // signal that this channel processing is done
+		if se == sortingCompleteSwapEvent || se == sortingAbandonedSwapEvent {
+			result.swaps = pr.eventCount
+			done()
 			return
 		}
 	}
 }
 
-func waitForEverythingComplete(msc chan string) {
-	var comparisonProcessingComplete = false
-	var swapProcessingComplete = false
-	var m string
-	for !comparisonProcessingComplete || !swapProcessingComplete {
-		m = <-msc
-		if m == ALL_COMPARISONS_COMPLETE_MESSAGE {
-			comparisonProcessingComplete = true
+func processWriteChannel(ctx context.Context, c chan WriteEvent, result *algorithmResult, settings progressSettings, observer eventObserver, done func()) {
+	var pr *progressReporter = newProgressReporter(result, "writes", settings)
+	var we WriteEvent
+	for true {
+		if !receiveWrite(ctx, c, &we) {
+			result.writes = pr.eventCount
+			done()
+			return
 		}
-		if m == ALL_SWAPS_COMPLETE_MESSAGE {
-			swapProcessingComplete = true
+		pr.record(we.knownToBeSortedCount)
+		if we.knownToBeSortedCount >= 0 {
+			observer.observeWrite(result.algorithm, we)
+		}
+		if we == sortingCompleteWriteEvent || we == sortingAbandonedWriteEvent {
+			result.writes = pr.eventCount
+			done()
+			return
 		}
 	}
 }
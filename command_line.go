@@ -0,0 +1,274 @@
+package main
+
+/*
+Parallel Sorting Demo
+Copyright (C) 2020 Robert Sheridan
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"time"
+)
+
+var algorithmsFlag = flag.String("algorithms", "", "comma separated list of algorithms to race, e.g. quick,shell (default all except random)")
+var sizeFlag = flag.Int("size", 1000, "number of elements to sort")
+var seedFlag = flag.Int64("seed", 0, "seed for generating randomized data (default taken from the clock)")
+var distributionFlag = flag.String("distribution", DISTRIBUTION_RANDOM, "arrangement of the data to sort: "+dataDistributionNames())
+var inversionsFlag = flag.Int64("inversions", 10, "number of out of order pairs in the k-inversions distribution (at most size*(size-1)/2, when the data is reversed)")
+var reportStepFlag = flag.Float64("report-step", 0.2, "proportion of the data which must become sorted between progress reports (0.001 to 1)")
+var traceFlag = flag.String("trace", "", "file to record every event to, as JSON Lines")
+var binaryTraceFlag = flag.String("binary-trace", "", "file to record every event to, in the compact binary trace format")
+var verifyFlag = flag.Bool("verify", false, "check each algorithm's event stream for inconsistencies after the race")
+var verifyTraceFlag = flag.String("verify-trace", "", "check the event streams of a recorded trace file for inconsistencies instead of racing")
+var dashboardFlag = flag.Bool("dashboard", false, "show a live dashboard with a progress bar per algorithm (plain progress lines when not on a terminal)")
+var visualizeFlag = flag.Bool("visualize", false, "animate the data of every algorithm as bars on the terminal (plain progress lines when not on a terminal)")
+var frameRateFlag = flag.Int("frame-rate", 20, "frames per second drawn by --visualize (1 to 100)")
+var svgFlag = flag.String("svg", "", "file to write an animated SVG of the race to")
+var svgFramesFlag = flag.Int("svg-frames", 200, "most frames in the animated SVG - larger inputs combine several events in each frame")
+var gifFlag = flag.String("gif", "", "file to write an animated GIF of the race to, one grid cell per algorithm in the order raced")
+var pngFramesFlag = flag.String("png-frames", "", "directory to write the frames of the race to, as numbered PNG images")
+var frameEventsFlag = flag.Int("frame-events", 0, "events of each algorithm drawn in each GIF or PNG frame (default fits the race in 100 frames)")
+var listenFlag = flag.String("listen", "localhost:8080", "address the serve command listens on")
+var speedFlag = flag.Float64("speed", 0, "events per second each algorithm may emit, to slow the race down for watching (default full speed)")
+var lockstepFlag = flag.Bool("lockstep", false, "race fairly: every algorithm waits for a central clock and takes the cost of each operation in ticks, so the finish order is decided by the work done")
+var comparisonCostFlag = flag.Int64("comparison-cost", 1, "ticks taken by a comparison in a --lockstep race")
+var swapCostFlag = flag.Int64("swap-cost", 1, "ticks taken by a swap in a --lockstep race")
+var writeCostFlag = flag.Int64("write-cost", 1, "ticks taken by a write to or from an auxiliary buffer in a --lockstep race")
+var timeoutFlag = flag.Duration("timeout", 0, "cancel every algorithm still sorting after this long, e.g. 30s (default no limit)")
+var deadlinesFlag = flag.String("deadlines", "", "comma separated time limits for particular algorithms, e.g. bubble=2s,insertion=500ms")
+var outputFlag = flag.String("output", OUTPUT_FORMAT_TEXT, "format of the output: "+strings.Join(outputFormats, ", "))
+
+// runOptions holds the validated command line settings for a run
+type runOptions struct {
+	algorithms       []int
+	data             dataSettings
+	output           string
+	reportPeriodStep float32
+	tracePath        string
+	binaryTracePath  string
+	verify           bool
+	verifyTracePath  string
+	dashboard        bool
+	visualize        bool
+	frameRate        int
+	svgPath          string
+	svgFrameBudget   int
+	gifPath          string
+	pngFramesPath    string
+	eventsPerFrame   int
+	serve            bool
+	listenAddress    string
+	quiet            bool               // no progress lines, as when racing for the web server
+	controller       *runController     // pauses and paces the routines, or nil to let them run freely
+	scheduler        *lockstepScheduler // races the routines in lockstep, or nil to let them race freely
+	timeout          time.Duration      // how long the whole race may take, or 0 for no limit
+	deadlines        map[int]time.Duration
+}
+
+// showsDashboard tells whether the live dashboard replaces the progress lines
+func (options runOptions) showsDashboard() bool {
+	return options.dashboard && isTerminal(os.Stdout)
+}
+
+// showsVisualization tells whether the animated bars replace the progress lines
+func (options runOptions) showsVisualization() bool {
+	return options.visualize && isTerminal(os.Stdout)
+}
+
+// progressOutput is where the progress lines of each algorithm are written
+func (options runOptions) progressOutput() io.Writer {
+	if options.quiet || options.showsDashboard() || options.showsVisualization() {
+		return io.Discard
+	}
+	return options.messageOutput()
+}
+
+// collectsEvents tells whether the events of the race are needed once it is over
+func (options runOptions) collectsEvents() bool {
+	return options.verify || options.svgPath != "" || options.gifPath != "" || options.pngFramesPath != ""
+}
+
+// messageOutput is where progress messages are written - standard error when standard output carries a JSON or CSV summary
+func (options runOptions) messageOutput() io.Writer {
+	if options.output == OUTPUT_FORMAT_TEXT {
+		return os.Stdout
+	}
+	return os.Stderr
+}
+
+func registeredAlgorithmNames() string {
+	var names []string = make([]string, 0, len(sortAlgorithmRegistry))
+	for _, algorithm := range registeredAlgorithms() {
+		names = append(names, shortAlgorithmName(algorithm))
+	}
+	return strings.Join(names, ", ")
+}
+
+func parseAlgorithmList(list string) ([]int, error) {
+	if strings.TrimSpace(list) == "" {
+		return defaultAlgorithms(), nil
+	}
+	var algorithms []int = make([]int, 0)
+	var chosen = map[int]bool{}
+	for _, name := range strings.Split(list, ",") {
+		algorithm, found := findRegisteredAlgorithm(name)
+		if !found {
+			return nil, fmt.Errorf("unknown algorithm %q - registered algorithms are: %s", strings.TrimSpace(name), registeredAlgorithmNames())
+		}
+		if !chosen[algorithm] {
+			chosen[algorithm] = true
+			algorithms = append(algorithms, algorithm)
+		}
+	}
+	return algorithms, nil
+}
+
+// parseDeadlines reads a list of algorithm=duration pairs
+func parseDeadlines(list string) (map[int]time.Duration, error) {
+	var deadlines = map[int]time.Duration{}
+	if strings.TrimSpace(list) == "" {
+		return deadlines, nil
+	}
+	for _, pair := range strings.Split(list, ",") {
+		name, limit, found := strings.Cut(pair, "=")
+		if !found {
+			return nil, fmt.Errorf("deadline %q is not of the form algorithm=duration", strings.TrimSpace(pair))
+		}
+		algorithm, found := findRegisteredAlgorithm(name)
+		if !found {
+			return nil, fmt.Errorf("unknown algorithm %q - registered algorithms are: %s", strings.TrimSpace(name), registeredAlgorithmNames())
+		}
+		deadline, err := time.ParseDuration(strings.TrimSpace(limit))
+		if err != nil || deadline <= 0 {
+			return nil, fmt.Errorf("deadline for %s must be a positive duration such as 2s", shortAlgorithmName(algorithm))
+		}
+		deadlines[algorithm] = deadline
+	}
+	return deadlines, nil
+}
+
+func isOneOf(value string, allowed []string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+	return false
+}
+
+// parseRunOptions reads the command line flags and checks that they describe a valid run
+func parseRunOptions() (runOptions, error) {
+	var options runOptions
+	flag.Parse()
+	if flag.Arg(0) == SERVE_COMMAND {
+		// flags may follow the command too
+		options.serve = true
+		if err := flag.CommandLine.Parse(flag.Args()[1:]); err != nil {
+			return options, err
+		}
+	}
+	algorithms, err := parseAlgorithmList(*algorithmsFlag)
+	if err != nil {
+		return options, err
+	}
+	options.algorithms = algorithms
+	if *sizeFlag < 0 || *sizeFlag > math.MaxInt32 {
+		return options, fmt.Errorf("size must be between 0 and %d", math.MaxInt32)
+	}
+	options.data.size = int32(*sizeFlag)
+	options.data.seed = time.Now().UnixNano()
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "seed" {
+			options.data.seed = *seedFlag
+		}
+	})
+	if _, found := findDataDistribution(*distributionFlag); !found {
+		return options, fmt.Errorf("unknown distribution %q - available distributions are: %s", *distributionFlag, dataDistributionNames())
+	}
+	options.data.distribution = *distributionFlag
+	if *inversionsFlag < 0 {
+		return options, fmt.Errorf("inversions must not be negative")
+	}
+	options.data.inversions = *inversionsFlag
+	if !isOneOf(*outputFlag, outputFormats) {
+		return options, fmt.Errorf("unknown output format %q - available formats are: %s", *outputFlag, strings.Join(outputFormats, ", "))
+	}
+	options.output = *outputFlag
+	if !(*reportStepFlag >= 0.001 && *reportStepFlag <= 1) {
+		return options, fmt.Errorf("report-step must be between 0.001 and 1")
+	}
+	options.reportPeriodStep = float32(*reportStepFlag)
+	options.tracePath = *traceFlag
+	options.binaryTracePath = *binaryTraceFlag
+	options.verify = *verifyFlag
+	options.verifyTracePath = *verifyTraceFlag
+	options.dashboard = *dashboardFlag
+	if options.dashboard && options.output != OUTPUT_FORMAT_TEXT {
+		return options, fmt.Errorf("the dashboard can only be shown with %s output", OUTPUT_FORMAT_TEXT)
+	}
+	options.visualize = *visualizeFlag
+	if options.visualize && options.output != OUTPUT_FORMAT_TEXT {
+		return options, fmt.Errorf("the visualization can only be shown with %s output", OUTPUT_FORMAT_TEXT)
+	}
+	if options.visualize && options.dashboard {
+		return options, fmt.Errorf("the dashboard and the visualization cannot be shown together")
+	}
+	if *frameRateFlag < 1 || *frameRateFlag > 100 {
+		return options, fmt.Errorf("frame-rate must be between 1 and 100")
+	}
+	options.frameRate = *frameRateFlag
+	options.svgPath = *svgFlag
+	if *svgFramesFlag < 1 {
+		return options, fmt.Errorf("svg-frames must be at least 1")
+	}
+	options.svgFrameBudget = *svgFramesFlag
+	options.gifPath = *gifFlag
+	options.pngFramesPath = *pngFramesFlag
+	if *frameEventsFlag < 0 {
+		return options, fmt.Errorf("frame-events must not be negative")
+	}
+	options.eventsPerFrame = *frameEventsFlag
+	options.listenAddress = *listenFlag
+	if err := checkSpeed(*speedFlag); err != nil {
+		return options, fmt.Errorf("speed: %v", err)
+	}
+	if *speedFlag > 0 {
+		options.controller = newRunController()
+		options.controller.setSpeed(*speedFlag)
+	}
+	if *comparisonCostFlag < 1 || *swapCostFlag < 1 || *writeCostFlag < 1 {
+		return options, fmt.Errorf("comparison-cost, swap-cost and write-cost must be at least 1")
+	}
+	if *lockstepFlag {
+		options.scheduler = newLockstepScheduler(*comparisonCostFlag, *swapCostFlag, *writeCostFlag)
+	}
+	if *timeoutFlag < 0 {
+		return options, fmt.Errorf("timeout must not be negative")
+	}
+	options.timeout = *timeoutFlag
+	if options.deadlines, err = parseDeadlines(*deadlinesFlag); err != nil {
+		return options, err
+	}
+	if len(flag.Args()) > 0 {
+		return options, fmt.Errorf("unexpected argument %q", flag.Arg(0))
+	}
+	return options, nil
+}
@@ -41,7 +41,25 @@ var algorithmName = []string{
 	"random sort",
 }
 
+// where a routine keeps the elements it counts in knownToBeSortedCount
+const SORTED_REGION_HEAD int = 1         // the first knownToBeSortedCount positions
+const SORTED_REGION_TAIL int = 2         // the last knownToBeSortedCount positions
+const SORTED_REGION_SCATTERED int = 3    // positions spread through the data
+const SORTED_REGION_ORDERED_HEAD int = 4 // the first knownToBeSortedCount positions, which are in order but may still move apart
+
 const SORTING_COMPLETE_VALUE int32 = -1
+const SORTING_ABANDONED_VALUE int32 = -2
+
+const RANDOM_SORT_DEFAULT_MAX_ATTEMPTS int32 = 1000
+
+const SERVE_COMMAND string = "serve"
 
-const ALL_COMPARISONS_COMPLETE_MESSAGE string = "all comparisons complete"
-const ALL_SWAPS_COMPLETE_MESSAGE string = "all swaps complete"
+const OUTPUT_FORMAT_TEXT string = "text"
+const OUTPUT_FORMAT_JSON string = "json"
+const OUTPUT_FORMAT_CSV string = "csv"
+
+var outputFormats = []string{
+	OUTPUT_FORMAT_TEXT,
+	OUTPUT_FORMAT_JSON,
+	OUTPUT_FORMAT_CSV,
+}
@@ -0,0 +1,275 @@
+package main
+
+/*
+Parallel Sorting Demo
+Copyright (C) 2020 Robert Sheridan
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+const DISTRIBUTION_RANDOM string = "random"
+const DISTRIBUTION_SORTED string = "sorted"
+const DISTRIBUTION_REVERSED string = "reversed"
+const DISTRIBUTION_NEARLY_SORTED string = "nearly-sorted"
+const DISTRIBUTION_FEW_UNIQUE string = "few-unique"
+const DISTRIBUTION_ORGAN_PIPE string = "organ-pipe"
+const DISTRIBUTION_SAWTOOTH string = "sawtooth"
+const DISTRIBUTION_K_INVERSIONS string = "k-inversions"
+
+const FEW_UNIQUE_VALUE_COUNT int32 = 8
+const SAWTOOTH_TEETH_COUNT int32 = 4
+const NEARLY_SORTED_MAX_DISPLACEMENT int32 = 5
+
+// dataSettings describes the data to generate for a run
+type dataSettings struct {
+	distribution string
+	size         int32
+	seed         int64
+	inversions   int64 // the number of inversions for the k-inversions distribution
+}
+
+// dataGenerator creates size elements, taking any randomness it needs from randomGenerator
+type dataGenerator func(settings dataSettings, randomGenerator *rand.Rand) []int32
+
+type dataDistribution struct {
+	name        string
+	description string
+	generate    dataGenerator
+}
+
+var dataDistributions = []dataDistribution{
+	{DISTRIBUTION_RANDOM, "a uniformly shuffled permutation", makeShuffledData},
+	{DISTRIBUTION_SORTED, "already in ascending order", makeSortedData},
+	{DISTRIBUTION_REVERSED, "in descending order", makeReversedData},
+	{DISTRIBUTION_NEARLY_SORTED, "ascending, with a tenth of the elements moved a few places", makeNearlySortedData},
+	{DISTRIBUTION_FEW_UNIQUE, fmt.Sprintf("shuffled, with only %d distinct values", FEW_UNIQUE_VALUE_COUNT), makeFewUniqueData},
+	{DISTRIBUTION_ORGAN_PIPE, "ascending to a peak in the middle, then descending", makeOrganPipeData},
+	{DISTRIBUTION_SAWTOOTH, fmt.Sprintf("%d ascending runs", SAWTOOTH_TEETH_COUNT), makeSawtoothData},
+	{DISTRIBUTION_K_INVERSIONS, "ascending, with exactly --inversions pairs out of order", makeKInversionsData},
+}
+
+func dataDistributionNames() string {
+	var names []string = make([]string, 0, len(dataDistributions))
+	for _, distribution := range dataDistributions {
+		names = append(names, distribution.name)
+	}
+	return strings.Join(names, ", ")
+}
+
+func findDataDistribution(name string) (dataDistribution, bool) {
+	for _, distribution := range dataDistributions {
+		if distribution.name == name {
+			return distribution, true
+		}
+	}
+	return dataDistribution{}, false
+}
+
+// checkDataSettings rejects settings which makeDataArray could not generate, such as those read from a damaged trace
+func checkDataSettings(settings dataSettings) error {
+	if _, found := findDataDistribution(settings.distribution); !found {
+		return fmt.Errorf("unknown distribution %q", settings.distribution)
+	}
+	if settings.size < 0 {
+		return fmt.Errorf("size %d is negative", settings.size)
+	}
+	if settings.inversions < 0 {
+		return fmt.Errorf("inversions %d is negative", settings.inversions)
+	}
+	return nil
+}
+
+// makeDataArray generates the data described by settings - the same settings always give the same data
+func makeDataArray(settings dataSettings) []int32 {
+	distribution, found := findDataDistribution(settings.distribution)
+	if !found {
+		panic("unknown data distribution " + settings.distribution)
+	}
+	return distribution.generate(settings, rand.New(rand.NewSource(settings.seed)))
+}
+
+func makeSortedDataArray(size int32) []int32 {
+	data := make([]int32, 0, size)
+	var pos int32
+	for pos = 0; pos < size; pos = pos + 1 {
+		data = append(data, pos)
+	}
+	return data
+}
+
+// shuffleDataArray moves every element to a uniformly random position (Fisher-Yates)
+func shuffleDataArray(data []int32, randomGenerator *rand.Rand) {
+	var pos int32
+	for pos = int32(len(data)) - 1; pos > 0; pos = pos - 1 {
+		var pos2 int32 = randomGenerator.Int31n(pos + 1)
+		temp := data[pos]
+		data[pos] = data[pos2]
+		data[pos2] = temp
+	}
+}
+
+func makeShuffledData(settings dataSettings, randomGenerator *rand.Rand) []int32 {
+	data := makeSortedDataArray(settings.size)
+	shuffleDataArray(data, randomGenerator)
+	return data
+}
+
+func makeSortedData(settings dataSettings, randomGenerator *rand.Rand) []int32 {
+	return makeSortedDataArray(settings.size)
+}
+
+func makeReversedData(settings dataSettings, randomGenerator *rand.Rand) []int32 {
+	data := make([]int32, 0, settings.size)
+	var pos int32
+	for pos = settings.size - 1; pos >= 0; pos = pos - 1 {
+		data = append(data, pos)
+	}
+	return data
+}
+
+// every tenth position (chosen at random) is swapped with an element a few places further on
+func makeNearlySortedData(settings dataSettings, randomGenerator *rand.Rand) []int32 {
+	data := makeSortedDataArray(settings.size)
+	var swapCount int32 = settings.size / 10
+	var swap int32
+	for swap = 0; swap < swapCount; swap = swap + 1 {
+		var pos int32 = randomGenerator.Int31n(settings.size)
+		var pos2 int32 = pos + 1 + randomGenerator.Int31n(NEARLY_SORTED_MAX_DISPLACEMENT)
+		if pos2 >= settings.size {
+			pos2 = settings.size - 1
+		}
+		temp := data[pos]
+		data[pos] = data[pos2]
+		data[pos2] = temp
+	}
+	return data
+}
+
+func makeFewUniqueData(settings dataSettings, randomGenerator *rand.Rand) []int32 {
+	data := make([]int32, 0, settings.size)
+	var pos int32
+	for pos = 0; pos < settings.size; pos = pos + 1 {
+		data = append(data, pos%FEW_UNIQUE_VALUE_COUNT)
+	}
+	shuffleDataArray(data, randomGenerator)
+	return data
+}
+
+// even values ascend to the middle of the list and odd values descend from there
+func makeOrganPipeData(settings dataSettings, randomGenerator *rand.Rand) []int32 {
+	data := make([]int32, settings.size)
+	var middle int32 = (settings.size + 1) / 2
+	var pos int32
+	for pos = 0; pos < settings.size; pos = pos + 1 {
+		if pos < middle {
+			data[pos] = 2 * pos
+		} else {
+			data[pos] = 2*(settings.size-1-pos) + 1
+		}
+	}
+	return data
+}
+
+func makeSawtoothData(settings dataSettings, randomGenerator *rand.Rand) []int32 {
+	data := make([]int32, settings.size)
+	var toothLength int32 = (settings.size + SAWTOOTH_TEETH_COUNT - 1) / SAWTOOTH_TEETH_COUNT
+	var pos int32
+	for pos = 0; pos < settings.size; pos = pos + 1 {
+		data[pos] = pos % toothLength
+	}
+	return data
+}
+
+// choose how many smaller elements follow each position (the Lehmer code of the permutation), adding up to the inversions,
+// then build the permutation from it - limited to the largest possible number of inversions, where the data is reversed
+// positions are visited in random order, each taking a random share of about the inversions left per position left
+func makeKInversionsData(settings dataSettings, randomGenerator *rand.Rand) []int32 {
+	var size int32 = settings.size
+	var inversions int64 = settings.inversions
+	var limit int64 = int64(size) * int64(size-1) / 2
+	if inversions > limit {
+		inversions = limit
+	}
+	var smallerAfter []int32 = make([]int32, size) // at most size-1-pos for each pos
+	var capacityLeft int64 = limit
+	var positionsLeft int64 = int64(size)
+	for _, pos := range randomGenerator.Perm(int(size)) {
+		var capacity int64 = int64(size) - 1 - int64(pos)
+		capacityLeft = capacityLeft - capacity
+		var lowest int64 = inversions - capacityLeft // what the positions still to visit cannot take
+		if lowest < 0 {
+			lowest = 0
+		}
+		var highest int64 = twiceTheShareOf(inversions, positionsLeft, randomGenerator)
+		if highest > capacity {
+			highest = capacity
+		}
+		if highest > inversions {
+			highest = inversions
+		}
+		var count int64 = lowest
+		if highest > lowest {
+			count = lowest + randomGenerator.Int63n(highest-lowest+1)
+		}
+		smallerAfter[pos] = int32(count)
+		inversions = inversions - count
+		positionsLeft = positionsLeft - 1
+	}
+	return permutationFromLehmerCode(smallerAfter)
+}
+
+// twiceTheShareOf divides twice the total between count shares, rounding at random so that the result is right on average
+func twiceTheShareOf(total int64, count int64, randomGenerator *rand.Rand) int64 {
+	return (2*(total%count)+randomGenerator.Int63n(count))/count + 2*(total/count)
+}
+
+// permutationFromLehmerCode places at each position the smallest value not yet placed but for smallerAfter[pos] of them,
+// finding it in a Fenwick tree of the values not yet placed, so that the whole permutation takes O(n log n)
+func permutationFromLehmerCode(smallerAfter []int32) []int32 {
+	var size int = len(smallerAfter)
+	var unplaced []int32 = make([]int32, size+1) // Fenwick tree counting the unplaced values, indexed from 1
+	for index := 1; index <= size; index = index + 1 {
+		unplaced[index] = unplaced[index] + 1
+		if parent := index + index&-index; parent <= size {
+			unplaced[parent] = unplaced[parent] + unplaced[index]
+		}
+	}
+	var highestBit int = 1
+	for highestBit*2 <= size {
+		highestBit = highestBit * 2
+	}
+	var data []int32 = make([]int32, size)
+	for pos, skip := range smallerAfter {
+		// descend the tree to the last index with at most skip unplaced values up to it - the value after it is the one to place
+		var index int = 0
+		var remaining int32 = skip
+		for bit := highestBit; bit > 0; bit = bit / 2 {
+			if index+bit <= size && unplaced[index+bit] <= remaining {
+				index = index + bit
+				remaining = remaining - unplaced[index]
+			}
+		}
+		data[pos] = int32(index) // the value index+1 in the tree, which counts from 1
+		for index = index + 1; index <= size; index = index + index&-index {
+			unplaced[index] = unplaced[index] - 1
+		}
+	}
+	return data
+}
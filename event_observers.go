@@ -0,0 +1,90 @@
+package main
+
+/*
+Parallel Sorting Demo
+Copyright (C) 2020 Robert Sheridan
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import "sync"
+
+// eventObserver is notified of every event emitted by a routine once it has been taken from the routine's channel
+// (the final events signalling completion are not observed)
+// the processors of each channel call observers concurrently, so implementations must be safe for concurrent use
+type eventObserver interface {
+	observeComparison(algorithm int, ce ComparisonEvent)
+	observeSwap(algorithm int, se SwapEvent)
+	observeWrite(algorithm int, we WriteEvent)
+}
+
+// eventObservers passes each event on to every observer in the list
+type eventObservers []eventObserver
+
+func (observers eventObservers) observeComparison(algorithm int, ce ComparisonEvent) {
+	for _, observer := range observers {
+		observer.observeComparison(algorithm, ce)
+	}
+}
+
+func (observers eventObservers) observeSwap(algorithm int, se SwapEvent) {
+	for _, observer := range observers {
+		observer.observeSwap(algorithm, se)
+	}
+}
+
+func (observers eventObservers) observeWrite(algorithm int, we WriteEvent) {
+	for _, observer := range observers {
+		observer.observeWrite(algorithm, we)
+	}
+}
+
+func (observers eventObservers) observeLifecycle(event lifecycleEvent) {
+	for _, observer := range observers {
+		if lo, isLifecycleObserver := observer.(lifecycleObserver); isLifecycleObserver {
+			lo.observeLifecycle(event)
+		}
+	}
+}
+
+// traceEventCollector is an eventObserver which keeps every event in memory, for replay or export once the race is over
+type traceEventCollector struct {
+	mutex  sync.Mutex
+	events []traceEvent
+}
+
+func (collector *traceEventCollector) add(event traceEvent) {
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+	collector.events = append(collector.events, event)
+}
+
+func (collector *traceEventCollector) observeComparison(algorithm int, ce ComparisonEvent) {
+	collector.add(traceEvent{algorithm: algorithm, kind: TRACE_EVENT_COMPARISON, comparison: ce})
+}
+
+func (collector *traceEventCollector) observeSwap(algorithm int, se SwapEvent) {
+	collector.add(traceEvent{algorithm: algorithm, kind: TRACE_EVENT_SWAP, swap: se})
+}
+
+func (collector *traceEventCollector) observeWrite(algorithm int, we WriteEvent) {
+	collector.add(traceEvent{algorithm: algorithm, kind: TRACE_EVENT_WRITE, write: we})
+}
+
+// collectedEvents returns the events observed so far
+func (collector *traceEventCollector) collectedEvents() []traceEvent {
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+	return append([]traceEvent(nil), collector.events...)
+}
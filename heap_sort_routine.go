@@ -0,0 +1,95 @@
+package main
+
+/*
+Parallel Sorting Demo
+Copyright (C) 2020 Robert Sheridan
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import "context"
+
+// HeapSortRoutine - sorts by arranging the list into a max heap and repeatedly moving the largest element to the end of the list
+type HeapSortRoutine struct {
+	sortRoutineBase
+}
+
+// NewHeapSortRoutine factory
+func NewHeapSortRoutine(startSlice []int32) *HeapSortRoutine {
+	hsr := new(HeapSortRoutine)
+	hsr.sortRoutineBase = newSortRoutineBase(startSlice)
+	return hsr
+}
+
+func init() {
+	registerSortAlgorithm(ALGORITHM_HEAP_SORT, registeredSortAlgorithm{
+		factory:           func(startSlice []int32) SortRoutine { return NewHeapSortRoutine(startSlice) },
+		includedByDefault: true,
+		sortedRegion:      SORTED_REGION_TAIL,
+	})
+}
+
+// leftChildInHeap finds the first child of root, and whether it is one of the elements before end which make up the heap
+func leftChildInHeap(root int32, end int32) (int32, bool) {
+	if root >= end/2 { // the same as 2*root+1 >= end, without overflowing for large heaps
+		return 0, false
+	}
+	return 2*root + 1, true
+}
+
+// move the element at root down the heap until both children are smaller (only elements before end are part of the heap)
+func (hsr HeapSortRoutine) siftDown(root int32, end int32) {
+	for {
+		child, found := leftChildInHeap(root, end)
+		if !found {
+			return
+		}
+		if child+1 < end && hsr.compareElementsAt(child, child+1) {
+			// the right child is larger
+			child = child + 1
+		}
+		if !hsr.compareElementsAt(root, child) {
+			// root is not smaller than its largest child - heap is in order
+			return
+		}
+		hsr.swapElementsAt(root, child)
+		root = child
+	}
+}
+
+/* Heap Sort
+ * arrange the list into a max heap by sifting down every parent element, starting with the last parent
+ * swap the largest element (at the head of the heap) with the last element of the heap
+ * the swapped element is now in its final position, so shrink the heap by one and sift the new head down
+ * repeat until the heap holds a single element
+ */
+func (hsr HeapSortRoutine) run(ctx context.Context) {
+	var root int32
+	for root = hsr.dataSize/2 - 1; root >= 0; root = root - 1 {
+		if ctx.Err() != nil {
+			return
+		}
+		hsr.siftDown(root, hsr.dataSize)
+	}
+	var end int32
+	for end = hsr.dataSize - 1; end > 0; end = end - 1 {
+		if ctx.Err() != nil {
+			return
+		}
+		hsr.swapElementsAt(0, end)
+		hsr.knownToBeSortedCount = hsr.knownToBeSortedCount + 1 // largest remaining element is in its final position
+		hsr.siftDown(0, end)
+	}
+	hsr.sortingRoutineComplete()
+}
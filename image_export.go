@@ -0,0 +1,192 @@
+package main
+
+/*
+Parallel Sorting Demo
+Copyright (C) 2020 Robert Sheridan
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+const IMAGE_CELL_WIDTH int = 320
+const IMAGE_CELL_HEIGHT int = 180
+const IMAGE_CELL_MARGIN int = 4
+const IMAGE_DEFAULT_FRAME_BUDGET int = 100
+const GIF_FRAME_DELAY int = 5         // hundredths of a second
+const GIF_FINAL_FRAME_DELAY int = 200 // hundredths of a second
+
+// palette indexes used when drawing frames
+const (
+	IMAGE_BACKGROUND uint8 = iota
+	IMAGE_BORDER
+	IMAGE_BAR
+	IMAGE_COMPARED
+)
+
+var imagePalette = color.Palette{
+	color.RGBA{0xff, 0xff, 0xff, 0xff},
+	color.RGBA{0xcc, 0xcc, 0xcc, 0xff},
+	color.RGBA{0x4a, 0x90, 0xd9, 0xff},
+	color.RGBA{0xf5, 0xa6, 0x23, 0xff},
+}
+
+// raceFrameRenderer replays the events of every algorithm in lockstep and draws them as a grid of bar charts, one cell per algorithm in reading order
+type raceFrameRenderer struct {
+	replays        []*traceReplay
+	eventsPerFrame int
+	scale          barHeightScale
+	gridColumns    int
+	gridRows       int
+}
+
+// newRaceFrameRenderer prepares to draw one frame every eventsPerFrame events, or within IMAGE_DEFAULT_FRAME_BUDGET frames when eventsPerFrame is 0
+func newRaceFrameRenderer(startSlice []int32, events []traceEvent, algorithms []int, eventsPerFrame int) *raceFrameRenderer {
+	renderer := new(raceFrameRenderer)
+	for _, algorithm := range algorithms {
+		renderer.replays = append(renderer.replays, newTraceReplay(startSlice, events, algorithm))
+	}
+	renderer.eventsPerFrame = eventsPerFrame
+	if renderer.eventsPerFrame <= 0 {
+		renderer.eventsPerFrame = eventsPerFrameWithin(events, algorithms, IMAGE_DEFAULT_FRAME_BUDGET)
+	}
+	renderer.scale = newBarHeightScale(startSlice)
+	renderer.gridColumns = int(math.Ceil(math.Sqrt(float64(len(algorithms)))))
+	if renderer.gridColumns < 1 {
+		renderer.gridColumns = 1
+	}
+	renderer.gridRows = (len(algorithms) + renderer.gridColumns - 1) / renderer.gridColumns
+	return renderer
+}
+
+func (renderer *raceFrameRenderer) finished() bool {
+	for _, replay := range renderer.replays {
+		if !replay.finished() {
+			return false
+		}
+	}
+	return true
+}
+
+// render calls visit with the frame before any event and then with each following frame until every replay has finished
+// the image passed to visit is reused for the next frame
+func (renderer *raceFrameRenderer) render(visit func(frame *image.Paletted) error) error {
+	var bounds image.Rectangle = image.Rect(0, 0, renderer.gridColumns*IMAGE_CELL_WIDTH, renderer.gridRows*IMAGE_CELL_HEIGHT)
+	var frame *image.Paletted = image.NewPaletted(bounds, imagePalette)
+	var compared [][2]int32 = make([][2]int32, len(renderer.replays))
+	for r := range compared {
+		compared[r] = [2]int32{-1, -1}
+	}
+	for {
+		renderer.draw(frame, compared)
+		if err := visit(frame); err != nil {
+			return err
+		}
+		if renderer.finished() {
+			return nil
+		}
+		for r, replay := range renderer.replays {
+			var err error
+			compared[r], err = replay.advance(renderer.eventsPerFrame)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (renderer *raceFrameRenderer) draw(frame *image.Paletted, compared [][2]int32) {
+	for pos := range frame.Pix {
+		frame.Pix[pos] = IMAGE_BACKGROUND
+	}
+	for r, replay := range renderer.replays {
+		var cell image.Rectangle = image.Rect(0, 0, IMAGE_CELL_WIDTH, IMAGE_CELL_HEIGHT).Add(image.Pt((r%renderer.gridColumns)*IMAGE_CELL_WIDTH, (r/renderer.gridColumns)*IMAGE_CELL_HEIGHT))
+		var plot image.Rectangle = cell.Inset(IMAGE_CELL_MARGIN)
+		for x := cell.Min.X; x < cell.Max.X; x = x + 1 {
+			frame.SetColorIndex(x, cell.Max.Y-1, IMAGE_BORDER)
+		}
+		for y := cell.Min.Y; y < cell.Max.Y; y = y + 1 {
+			frame.SetColorIndex(cell.Max.X-1, y, IMAGE_BORDER)
+		}
+		if len(replay.data) == 0 || plot.Empty() {
+			continue
+		}
+		// each pixel column shows the element it falls on, so large inputs are sampled and small ones drawn as wide bars
+		for x := 0; x < plot.Dx(); x = x + 1 {
+			var pos int32 = int32(int64(x) * int64(len(replay.data)) / int64(plot.Dx()))
+			var colorIndex uint8 = IMAGE_BAR
+			if pos == compared[r][0] || pos == compared[r][1] {
+				colorIndex = IMAGE_COMPARED
+			}
+			var height int = int(math.Round(renderer.scale.barHeight(replay.data[pos], float64(plot.Dy()))))
+			for y := plot.Max.Y - height; y < plot.Max.Y; y = y + 1 {
+				frame.SetColorIndex(plot.Min.X+x, y, colorIndex)
+			}
+		}
+	}
+}
+
+// exportAnimatedGIF writes the race as an animated GIF, holding the final frame for a while before it loops
+func exportAnimatedGIF(path string, startSlice []int32, events []traceEvent, algorithms []int, eventsPerFrame int) error {
+	var animation gif.GIF
+	err := newRaceFrameRenderer(startSlice, events, algorithms, eventsPerFrame).render(func(frame *image.Paletted) error {
+		var copied *image.Paletted = image.NewPaletted(frame.Bounds(), frame.Palette)
+		_ = copy(copied.Pix, frame.Pix)
+		animation.Image = append(animation.Image, copied)
+		animation.Delay = append(animation.Delay, GIF_FRAME_DELAY)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	animation.Delay[len(animation.Delay)-1] = GIF_FINAL_FRAME_DELAY
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := gif.EncodeAll(file, &animation); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// exportPNGSequence writes each frame of the race to directory as frame-00000.png, frame-00001.png and so on
+func exportPNGSequence(directory string, startSlice []int32, events []traceEvent, algorithms []int, eventsPerFrame int) error {
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return err
+	}
+	var frameNumber int = 0
+	return newRaceFrameRenderer(startSlice, events, algorithms, eventsPerFrame).render(func(frame *image.Paletted) error {
+		file, err := os.Create(filepath.Join(directory, fmt.Sprintf("frame-%05d.png", frameNumber)))
+		if err != nil {
+			return err
+		}
+		frameNumber = frameNumber + 1
+		if err := png.Encode(file, frame); err != nil {
+			file.Close()
+			return err
+		}
+		return file.Close()
+	})
+}
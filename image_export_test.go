@@ -0,0 +1,34 @@
+package main
+
+import (
+	"image"
+	"testing"
+)
+
+func TestRaceFrameRendererDrawsOneFramePerInterval(t *testing.T) {
+	var algorithms []int = []int{ALGORITHM_BUBBLE_SORT, ALGORITHM_HEAP_SORT, ALGORITHM_MERGE_SORT}
+	var collector *traceEventCollector = new(traceEventCollector)
+	startSlice, _ := raceForTest(t, algorithms, 50, 11, collector)
+	var events []traceEvent = collector.collectedEvents()
+	var longest int = 0
+	for _, algorithm := range algorithms {
+		if count := len(eventsOfAlgorithm(events, algorithm)); count > longest {
+			longest = count
+		}
+	}
+	var renderer *raceFrameRenderer = newRaceFrameRenderer(startSlice, events, algorithms, 100)
+	var frames int = 0
+	err := renderer.render(func(frame *image.Paletted) error {
+		frames = frames + 1
+		if frame.Bounds().Dx() != 2*IMAGE_CELL_WIDTH || frame.Bounds().Dy() != 2*IMAGE_CELL_HEIGHT {
+			t.Fatalf("expected a 2x2 grid of cells, got a frame of %v", frame.Bounds())
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := (longest+99)/100 + 1; frames != expected {
+		t.Errorf("rendered %d frames for %d events, expected %d", frames, longest, expected)
+	}
+}
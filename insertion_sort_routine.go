@@ -18,49 +18,43 @@ You should have received a copy of the GNU Affero General Public License
 along with this program.  If not, see <https://www.gnu.org/licenses/>.
 */
 
+import "context"
+
 // InsertionSortRoutine - sorts by adding/moving one element at a time into the correct position in a sorted list
 type InsertionSortRoutine struct {
-	data                 []int32
-	dataSize             int32
-	swapChannel          chan SwapEvent
-	comparisonChannel    chan ComparisonEvent
-	knownToBeSortedCount int32
+	sortRoutineBase
 }
 
 // NewInsertionSortRoutine factory
 func NewInsertionSortRoutine(startSlice []int32) *InsertionSortRoutine {
 	isr := new(InsertionSortRoutine)
-	isr.dataSize = int32(len(startSlice))
-	isr.data = make([]int32, isr.dataSize)
-	_ = copy(isr.data, startSlice)
-	cc := make(chan ComparisonEvent, 1000)
-	isr.comparisonChannel = cc
-	sc := make(chan SwapEvent, 1000)
-	isr.swapChannel = sc
-	isr.knownToBeSortedCount = 0
+	isr.sortRoutineBase = newSortRoutineBase(startSlice)
 	return isr
 }
 
-func (isr InsertionSortRoutine) getComparisonChannel() chan ComparisonEvent {
-	return isr.comparisonChannel
-}
-
-func (isr InsertionSortRoutine) getSwapChannel() chan SwapEvent {
-	return isr.swapChannel
+func init() {
+	registerSortAlgorithm(ALGORITHM_INSERTION_SORT, registeredSortAlgorithm{
+		factory:           func(startSlice []int32) SortRoutine { return NewInsertionSortRoutine(startSlice) },
+		includedByDefault: true,
+		sortedRegion:      SORTED_REGION_ORDERED_HEAD,
+	})
 }
 
-func (isr InsertionSortRoutine) run() {
+func (isr InsertionSortRoutine) run(ctx context.Context) {
 	var top int32 = int32(0)
 	var bottom int32 = top
 	for bottom < int32(len(isr.data)-1) {
+		if ctx.Err() != nil {
+			return
+		}
 		var scanPos int32
 		for scanPos = bottom + 1; scanPos > top; scanPos = scanPos - 1 {
-			if compareElementsAt(isr.data, scanPos, scanPos-1, isr.knownToBeSortedCount, isr.comparisonChannel) {
-				swapElementsAt(isr.data, scanPos, scanPos-1, isr.knownToBeSortedCount, isr.swapChannel)
+			if isr.compareElementsAt(scanPos, scanPos-1) {
+				isr.swapElementsAt(scanPos, scanPos-1)
 			}
 		}
 		bottom = bottom + 1
 		isr.knownToBeSortedCount = bottom
 	}
-	sortingRoutineComplete(isr.comparisonChannel, isr.swapChannel)
+	isr.sortingRoutineComplete()
 }
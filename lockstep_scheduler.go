@@ -0,0 +1,106 @@
+package main
+
+/*
+Parallel Sorting Demo
+Copyright (C) 2020 Robert Sheridan
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import "sync"
+
+// lockstepScheduler is a central clock for a fair race: a routine's next comparison, swap or write waits until the clock grants it,
+// and the clock only moves on once every running routine is waiting, so each algorithm progresses by the cost of its operations
+// rather than by how the Go scheduler happens to share out the processors
+type lockstepScheduler struct {
+	mutex          sync.Mutex
+	changed        *sync.Cond
+	tick           int64
+	running        int
+	waiting        map[*routineControl]bool
+	comparisonCost int64 // ticks taken by a comparison
+	swapCost       int64 // ticks taken by a swap
+	writeCost      int64 // ticks taken by a write
+}
+
+func newLockstepScheduler(comparisonCost int64, swapCost int64, writeCost int64) *lockstepScheduler {
+	scheduler := new(lockstepScheduler)
+	scheduler.changed = sync.NewCond(&scheduler.mutex)
+	scheduler.waiting = map[*routineControl]bool{}
+	scheduler.comparisonCost = comparisonCost
+	scheduler.swapCost = swapCost
+	scheduler.writeCost = writeCost
+	return scheduler
+}
+
+// costOf gives the ticks taken by an event of eventKind (a TRACE_EVENT_ value)
+func (scheduler *lockstepScheduler) costOf(eventKind byte) int64 {
+	switch eventKind {
+	case TRACE_EVENT_COMPARISON:
+		return scheduler.comparisonCost
+	case TRACE_EVENT_SWAP:
+		return scheduler.swapCost
+	}
+	return scheduler.writeCost
+}
+
+// join counts a routine in, before it starts running
+func (scheduler *lockstepScheduler) join() {
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+	scheduler.running = scheduler.running + 1
+}
+
+// leave counts a routine out once it will emit no more events, letting the clock move on without it
+func (scheduler *lockstepScheduler) leave() {
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+	scheduler.running = scheduler.running - 1
+	scheduler.advance()
+}
+
+// advance moves the clock to the earliest tick a waiting routine is due at, once every running routine is waiting
+func (scheduler *lockstepScheduler) advance() {
+	if len(scheduler.waiting) == 0 || len(scheduler.waiting) < scheduler.running {
+		return
+	}
+	var next int64 = -1
+	for control := range scheduler.waiting {
+		if next < 0 || control.dueAtTick < next {
+			next = control.dueAtTick
+		}
+	}
+	scheduler.tick = next
+	scheduler.changed.Broadcast()
+}
+
+// wake lets waiting routines notice that they have been cancelled
+func (scheduler *lockstepScheduler) wake() {
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+	scheduler.changed.Broadcast()
+}
+
+// await blocks the routine until the clock has moved on by cost ticks, recording the tick it was granted
+func (scheduler *lockstepScheduler) await(control *routineControl, cost int64) {
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+	control.dueAtTick = scheduler.tick + cost
+	scheduler.waiting[control] = true
+	scheduler.advance()
+	for scheduler.tick < control.dueAtTick && control.context.Err() == nil {
+		scheduler.changed.Wait()
+	}
+	delete(scheduler.waiting, control)
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestLockstepRaceTakesTheCostOfEveryOperation(t *testing.T) {
+	var ticks = map[int]int64{}
+	for attempt := 0; attempt < 2; attempt = attempt + 1 {
+		var options runOptions = raceOptionsForTest(defaultAlgorithms(), 80, 13)
+		options.scheduler = newLockstepScheduler(1, 3, 2)
+		var results []*algorithmResult = runSortRace(context.Background(), makeDataArray(options.data), options, eventObservers{})
+		for _, result := range results {
+			var expected int64 = result.comparisons + 3*result.swaps + 2*result.writes
+			if result.ticks != expected {
+				t.Errorf("%s finished at tick %d, expected %d", algorithmName[result.algorithm], result.ticks, expected)
+			}
+			if attempt > 0 && result.ticks != ticks[result.algorithm] {
+				t.Errorf("%s finished at tick %d then at tick %d", algorithmName[result.algorithm], ticks[result.algorithm], result.ticks)
+			}
+			ticks[result.algorithm] = result.ticks
+		}
+		for _, result := range results {
+			for _, other := range results {
+				if result.ticks < other.ticks && result.rank > other.rank {
+					t.Errorf("%s finished before %s but was ranked after it", algorithmName[result.algorithm], algorithmName[other.algorithm])
+				}
+			}
+		}
+	}
+}
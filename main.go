@@ -19,41 +19,34 @@ along with this program.  If not, see <https://www.gnu.org/licenses/>.
 */
 
 import (
+	"context"
 	"fmt"
-	"math/rand"
+	"os"
 	"strconv"
-	"time"
 )
 
-func makeRandomizedDataArray(size int32) []int32 {
-	data := make([]int32, 0, size)
-	var pos int32
-	for pos = 0; pos < size; pos = pos + 1 {
-		data = append(data, pos)
-	}
-	randomSource := rand.NewSource(time.Now().UnixNano())
-	for pos = 0; pos < size; pos = pos + 1 {
-		var pos2 int32 = rand.New(randomSource).Int31n(size)
-		temp := data[pos]
-		data[pos] = data[pos2]
-		data[pos2] = temp
-	}
-	return data
-}
-
 func printDataArray(data []int32) {
 	for pos := 0; pos < len(data); pos = pos + 1 {
 		fmt.Println(data[pos])
 	}
 }
 
-func arrayIsSorted(data []int32) bool {
+// firstOutOfOrderPosition finds the first element which is larger than its successor, or returns -1 if data is sorted
+func firstOutOfOrderPosition(data []int32) int {
 	for pos := 0; pos < len(data)-1; pos = pos + 1 {
 		if data[pos] > data[pos+1] {
-			fmt.Println("Incorrect order: " + strconv.Itoa(int(data[pos])) + " was positioned before " + strconv.Itoa(int(data[pos+1])))
-			return false
+			return pos
 		}
 	}
+	return -1
+}
+
+func arrayIsSorted(data []int32) bool {
+	var pos int = firstOutOfOrderPosition(data)
+	if pos >= 0 {
+		fmt.Println("Incorrect order: " + strconv.Itoa(int(data[pos])) + " was positioned before " + strconv.Itoa(int(data[pos+1])))
+		return false
+	}
 	return true
 }
 
@@ -66,48 +59,137 @@ func reportFinalSortResults(data []int32, name string) {
 	}
 }
 
+// verifyTraceFile checks a recorded trace, exiting with status 1 if it cannot be read or is inconsistent
+func verifyTraceFile(path string) {
+	header, events, err := readTraceFile(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "could not read trace: "+err.Error())
+		os.Exit(1)
+	}
+	if reportTraceVerification(os.Stdout, makeDataArray(header.data), events, header.algorithms) > 0 {
+		os.Exit(1)
+	}
+}
+
 func main() {
-	var startSlice []int32 = makeRandomizedDataArray(1000)
-	// create supervisory channels and start processing
-	var masterSupervisorChannel chan string = make(chan string)
-	var compareSupervisorChannel chan int = make(chan int)
-	go monitorSupervisorChannel(compareSupervisorChannel, masterSupervisorChannel, ALL_COMPARISONS_COMPLETE_MESSAGE)
-	var swapSupervisorChannel chan int = make(chan int)
-	go monitorSupervisorChannel(swapSupervisorChannel, masterSupervisorChannel, ALL_SWAPS_COMPLETE_MESSAGE)
-	// create algorithm routines
-	var bsr *BubbleSortRoutine = NewBubbleSortRoutine(startSlice)
-	var ssr *SelectionSortRoutine = NewSelectionSortRoutine(startSlice)
-	var isr *InsertionSortRoutine = NewInsertionSortRoutine(startSlice)
-	var shsr *ShellSortRoutine = NewShellSortRoutine(startSlice)
-	var qsr *QuickSortRoutine = NewQuickSortRoutine(startSlice)
-	// start up algorithms and channel processors
-	startSupervisionOfSort(compareSupervisorChannel, swapSupervisorChannel, ALGORITHM_BUBBLE_SORT)
-	go processComparisonChannel(bsr.getComparisonChannel(), ALGORITHM_BUBBLE_SORT, compareSupervisorChannel)
-	go processSwapChannel(bsr.getSwapChannel(), ALGORITHM_BUBBLE_SORT, swapSupervisorChannel)
-	startSupervisionOfSort(compareSupervisorChannel, swapSupervisorChannel, ALGORITHM_SELECTION_SORT)
-	go processComparisonChannel(ssr.getComparisonChannel(), ALGORITHM_SELECTION_SORT, compareSupervisorChannel)
-	go processSwapChannel(ssr.getSwapChannel(), ALGORITHM_SELECTION_SORT, swapSupervisorChannel)
-	startSupervisionOfSort(compareSupervisorChannel, swapSupervisorChannel, ALGORITHM_INSERTION_SORT)
-	go processComparisonChannel(isr.getComparisonChannel(), ALGORITHM_INSERTION_SORT, compareSupervisorChannel)
-	go processSwapChannel(isr.getSwapChannel(), ALGORITHM_INSERTION_SORT, swapSupervisorChannel)
-	startSupervisionOfSort(compareSupervisorChannel, swapSupervisorChannel, ALGORITHM_SHELL_SORT)
-	go processComparisonChannel(shsr.getComparisonChannel(), ALGORITHM_SHELL_SORT, compareSupervisorChannel)
-	go processSwapChannel(shsr.getSwapChannel(), ALGORITHM_SHELL_SORT, swapSupervisorChannel)
-	startSupervisionOfSort(compareSupervisorChannel, swapSupervisorChannel, ALGORITHM_QUICK_SORT)
-	go processComparisonChannel(qsr.getComparisonChannel(), ALGORITHM_QUICK_SORT, compareSupervisorChannel)
-	go processSwapChannel(qsr.getSwapChannel(), ALGORITHM_QUICK_SORT, swapSupervisorChannel)
-	// start sorting algorithms
-	fmt.Println("beginning sorting routines")
-	go bsr.run()
-	go ssr.run()
-	go isr.run()
-	go shsr.run()
-	go qsr.run()
-	waitForEverythingComplete(masterSupervisorChannel)
-	reportFinalSortResults(bsr.data, "bubble sort")
-	reportFinalSortResults(ssr.data, "selection sort")
-	reportFinalSortResults(isr.data, "insertion sort")
-	reportFinalSortResults(ssr.data, "shell sort")
-	reportFinalSortResults(qsr.data, "quick sort")
-	fmt.Println("program complete")
+	options, err := parseRunOptions()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if options.serve {
+		serveRaces(options)
+		return
+	}
+	if options.verifyTracePath != "" {
+		verifyTraceFile(options.verifyTracePath)
+		return
+	}
+	distribution, _ := findDataDistribution(options.data.distribution)
+	fmt.Fprintf(options.messageOutput(), "generating %d elements with distribution %s (%s) and seed %d (rerun with --seed=%d to reproduce)\n", options.data.size, distribution.name, distribution.description, options.data.seed, options.data.seed)
+	var startSlice []int32 = makeDataArray(options.data)
+	var header traceHeader = traceHeader{options.data, options.algorithms}
+	var observers eventObservers
+	var recorder *jsonlTraceRecorder
+	if options.tracePath != "" {
+		recorder, err = newJSONLTraceRecorder(options.tracePath, header)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		observers = append(observers, recorder)
+	}
+	var binaryTrace *binaryTraceWriter
+	if options.binaryTracePath != "" {
+		binaryTrace, err = newBinaryTraceWriter(options.binaryTracePath, header)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		observers = append(observers, binaryTrace)
+	}
+	var collector *traceEventCollector
+	if options.collectsEvents() {
+		collector = new(traceEventCollector)
+		observers = append(observers, collector)
+	}
+	var dashboard *terminalDashboard
+	if options.showsDashboard() {
+		dashboard = newTerminalDashboard(os.Stdout, options.algorithms, int32(len(startSlice)))
+		observers = append(observers, dashboard)
+		dashboard.begin()
+	}
+	var visualizer *terminalVisualizer
+	if options.showsVisualization() {
+		width, height := terminalSize()
+		visualizer = newTerminalVisualizer(os.Stdout, options.algorithms, startSlice, options.frameRate, width, height)
+		observers = append(observers, visualizer)
+		visualizer.begin()
+	}
+	var ctx context.Context = context.Background()
+	if options.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, options.timeout)
+		defer cancel()
+	}
+	var results []*algorithmResult = runSortRace(ctx, startSlice, options, observers)
+	if dashboard != nil {
+		dashboard.end()
+	}
+	if visualizer != nil {
+		visualizer.end()
+	}
+	// reported only once the dashboard and visualization have stopped drawing, so nothing is drawn over the reports
+	for _, result := range results {
+		if result.panicked != nil {
+			result.panicked.report(os.Stderr, result.algorithm)
+		}
+	}
+	if recorder != nil {
+		if err := recorder.close(); err != nil {
+			fmt.Fprintln(os.Stderr, "could not record trace: "+err.Error())
+			os.Exit(1)
+		}
+	}
+	if binaryTrace != nil {
+		if err := binaryTrace.close(); err != nil {
+			fmt.Fprintln(os.Stderr, "could not record binary trace: "+err.Error())
+			os.Exit(1)
+		}
+	}
+	if options.output == OUTPUT_FORMAT_TEXT {
+		for _, result := range results {
+			if result.cancelled || result.panicked != nil {
+				fmt.Printf("%s %s.\n", algorithmName[result.algorithm], rankDescription(result))
+				continue
+			}
+			reportFinalSortResults(result.routine.getData(), algorithmName[result.algorithm])
+		}
+	}
+	if options.svgPath != "" {
+		if err := exportAnimatedSVG(options.svgPath, startSlice, collector.collectedEvents(), options.algorithms, options.svgFrameBudget); err != nil {
+			fmt.Fprintln(os.Stderr, "could not export animated SVG: "+err.Error())
+			os.Exit(1)
+		}
+	}
+	if options.gifPath != "" {
+		if err := exportAnimatedGIF(options.gifPath, startSlice, collector.collectedEvents(), options.algorithms, options.eventsPerFrame); err != nil {
+			fmt.Fprintln(os.Stderr, "could not export animated GIF: "+err.Error())
+			os.Exit(1)
+		}
+	}
+	if options.pngFramesPath != "" {
+		if err := exportPNGSequence(options.pngFramesPath, startSlice, collector.collectedEvents(), options.algorithms, options.eventsPerFrame); err != nil {
+			fmt.Fprintln(os.Stderr, "could not export PNG frames: "+err.Error())
+			os.Exit(1)
+		}
+	}
+	if options.verify {
+		reportTraceVerification(options.messageOutput(), startSlice, collector.collectedEvents(), options.algorithms)
+	}
+	if err := (runSummary{options.data, results}).write(os.Stdout, options.output); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Fprintln(options.messageOutput(), "program complete")
 }
@@ -1,9 +1,102 @@
 package main
 
-import "testing"
+import (
+	"sort"
+	"testing"
+	"time"
+)
 
 func BenchmarkMain(b *testing.B) {
 	for n := 0; n < b.N; n++ {
 		main()
 	}
 }
+
+func TestRandomDataIsReproducible(t *testing.T) {
+	const size int32 = 500
+	first := makeDataArray(dataSettings{DISTRIBUTION_RANDOM, size, 42, 0})
+	second := makeDataArray(dataSettings{DISTRIBUTION_RANDOM, size, 42, 0})
+	seen := make([]bool, size)
+	for pos := range first {
+		if first[pos] != second[pos] {
+			t.Fatalf("data generated with the same seed differs at position %d", pos)
+		}
+		if seen[first[pos]] {
+			t.Fatalf("value %d generated twice", first[pos])
+		}
+		seen[first[pos]] = true
+	}
+	other := makeDataArray(dataSettings{DISTRIBUTION_RANDOM, size, 43, 0})
+	differences := 0
+	for pos := range first {
+		if first[pos] != other[pos] {
+			differences = differences + 1
+		}
+	}
+	if differences == 0 {
+		t.Errorf("data generated with different seeds is identical")
+	}
+}
+
+// countInversions counts the pairs of data which are out of order, by counting them as they are merged
+func countInversions(data []int32) int64 {
+	if len(data) < 2 {
+		return 0
+	}
+	var middle int = len(data) / 2
+	var upper []int32 = append([]int32{}, data[:middle]...)
+	var lower []int32 = append([]int32{}, data[middle:]...)
+	var inversions int64 = countInversions(upper) + countInversions(lower)
+	var fromUpper, fromLower int = 0, 0
+	for pos := range data {
+		if fromLower >= len(lower) || (fromUpper < len(upper) && upper[fromUpper] <= lower[fromLower]) {
+			data[pos] = upper[fromUpper]
+			fromUpper = fromUpper + 1
+		} else {
+			data[pos] = lower[fromLower]
+			fromLower = fromLower + 1
+			inversions = inversions + int64(len(upper)-fromUpper)
+		}
+	}
+	return inversions
+}
+
+func TestKInversionsDataHasExactlyTheInversions(t *testing.T) {
+	var tests = []struct {
+		size       int32
+		inversions int64
+		expected   int64
+	}{
+		{0, 5, 0},
+		{1, 5, 0},
+		{2, 1, 1},
+		{10, 0, 0},
+		{10, 1, 1},
+		{10, 44, 44},
+		{10, 45, 45},
+		{10, 1000, 45}, // limited to the data reversed
+		{1000, 10, 10},
+		{1000, 250000, 250000},
+	}
+	for _, test := range tests {
+		var data []int32 = makeDataArray(dataSettings{DISTRIBUTION_K_INVERSIONS, test.size, 7, test.inversions})
+		var values []int32 = append([]int32{}, data...)
+		sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+		for pos := range values {
+			if values[pos] != int32(pos) {
+				t.Fatalf("%d elements with %d inversions are not a permutation: %v", test.size, test.inversions, data)
+			}
+		}
+		if inversions := countInversions(data); inversions != test.expected {
+			t.Errorf("%d elements asked for %d inversions have %d, expected %d", test.size, test.inversions, inversions, test.expected)
+		}
+	}
+	var start time.Time = time.Now()
+	var data []int32 = makeDataArray(dataSettings{DISTRIBUTION_K_INVERSIONS, 100000, 7, 2500000000})
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("generating 2500000000 inversions took %s", elapsed)
+	}
+	if inversions := countInversions(data); inversions != 2500000000 {
+		t.Errorf("100000 elements have %d inversions, expected 2500000000", inversions)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+/*
+Parallel Sorting Demo
+Copyright (C) 2020 Robert Sheridan
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import "context"
+
+// MergeSortRoutine - sorts by merging neighboring sorted sublists of doubling size through an auxiliary buffer
+type MergeSortRoutine struct {
+	sortRoutineBase
+	auxiliary []int32
+}
+
+// NewMergeSortRoutine factory
+func NewMergeSortRoutine(startSlice []int32) *MergeSortRoutine {
+	msr := new(MergeSortRoutine)
+	msr.sortRoutineBase = newSortRoutineBase(startSlice)
+	msr.auxiliary = make([]int32, msr.dataSize)
+	return msr
+}
+
+func init() {
+	registerSortAlgorithm(ALGORITHM_MERGE_SORT, registeredSortAlgorithm{
+		factory:           func(startSlice []int32) SortRoutine { return NewMergeSortRoutine(startSlice) },
+		includedByDefault: true,
+		sortedRegion:      SORTED_REGION_HEAD,
+	})
+}
+
+// merge the sorted ranges [top, middle] and [middle+1, bottom] of data into the same positions of the auxiliary buffer
+func (msr MergeSortRoutine) mergeIntoAuxiliary(top int32, middle int32, bottom int32) {
+	var fromUpper int32 = top
+	var fromLower int32 = middle + 1
+	var to int32
+	for to = top; to <= bottom; to = to + 1 {
+		// take from the upper range when its element is not larger, so equal elements keep their order
+		if fromLower > bottom || (fromUpper <= middle && !msr.compareElementsAt(fromLower, fromUpper)) {
+			msr.copyElementToAuxiliary(msr.auxiliary, to, fromUpper)
+			fromUpper = fromUpper + 1
+		} else {
+			msr.copyElementToAuxiliary(msr.auxiliary, to, fromLower)
+			fromLower = fromLower + 1
+		}
+	}
+}
+
+/* Merge Sort
+ * treat the list as sorted sublists of width 1
+ * merge each neighboring pair of sublists into the auxiliary buffer, then copy the merged range back into the list
+ * double the width and repeat until a single sublist spans the whole list
+ * elements only reach their final position when copied back during the last pass
+ */
+func (msr MergeSortRoutine) run(ctx context.Context) {
+	var width int32
+	for width = 1; width < msr.dataSize; width = width * 2 {
+		var lastPass bool = width >= msr.dataSize-width
+		var top int32 = 0
+		for top < msr.dataSize-width {
+			if ctx.Err() != nil {
+				return
+			}
+			var middle int32 = top + width - 1
+			var bottom int32 = lastPositionInRange(middle+1, width, msr.dataSize)
+			msr.mergeIntoAuxiliary(top, middle, bottom)
+			var pos int32
+			for pos = top; pos <= bottom; pos = pos + 1 {
+				msr.copyElementFromAuxiliary(msr.auxiliary, pos, pos)
+				if lastPass {
+					msr.knownToBeSortedCount = msr.knownToBeSortedCount + 1
+				}
+			}
+			top = bottom + 1
+		}
+		if lastPass {
+			break // doubling the width again could overflow
+		}
+	}
+	msr.sortingRoutineComplete()
+}
@@ -18,46 +18,38 @@ You should have received a copy of the GNU Affero General Public License
 along with this program.  If not, see <https://www.gnu.org/licenses/>.
 */
 
+import "context"
+
 // QuickSortRoutine - sorts by picking a pivot element and partitioning each sublist into a larger and a smaller partition. Recur.
 type QuickSortRoutine struct {
-	data                 []int32
-	dataSize             int32
-	swapChannel          chan SwapEvent
-	comparisonChannel    chan ComparisonEvent
-	knownToBeSortedCount int32
+	sortRoutineBase
 }
 
 // NewQuickSortRoutine factory
 func NewQuickSortRoutine(startSlice []int32) *QuickSortRoutine {
 	qsr := new(QuickSortRoutine)
-	qsr.dataSize = int32(len(startSlice))
-	qsr.data = make([]int32, qsr.dataSize)
-	_ = copy(qsr.data, startSlice)
-	cc := make(chan ComparisonEvent, 1000)
-	qsr.comparisonChannel = cc
-	sc := make(chan SwapEvent, 1000)
-	qsr.swapChannel = sc
-	qsr.knownToBeSortedCount = 0
+	qsr.sortRoutineBase = newSortRoutineBase(startSlice)
 	return qsr
 }
 
-func (qsr QuickSortRoutine) getComparisonChannel() chan ComparisonEvent {
-	return qsr.comparisonChannel
-}
-
-func (qsr QuickSortRoutine) getSwapChannel() chan SwapEvent {
-	return qsr.swapChannel
+func init() {
+	registerSortAlgorithm(ALGORITHM_QUICK_SORT, registeredSortAlgorithm{
+		factory:           func(startSlice []int32) SortRoutine { return NewQuickSortRoutine(startSlice) },
+		includedByDefault: true,
+		sortedRegion:      SORTED_REGION_SCATTERED,
+	})
 }
 
+// select the middle-sized of the first three elements of a range - only called for ranges longer than insertionSort is used for
 func (qsr QuickSortRoutine) selectPivot(top int32) int32 {
-	if compareElementsAt(qsr.data, top, top+1, qsr.knownToBeSortedCount, qsr.comparisonChannel) {
+	if qsr.compareElementsAt(top, top+1) {
 		// e0 < e1
-		if compareElementsAt(qsr.data, top+1, top+2, qsr.knownToBeSortedCount, qsr.comparisonChannel) {
+		if qsr.compareElementsAt(top+1, top+2) {
 			// e0 < e1 < e2
 			return top + 1
 		}
 		// e0 < e1 && e2 < e1
-		if compareElementsAt(qsr.data, top, top+2, qsr.knownToBeSortedCount, qsr.comparisonChannel) {
+		if qsr.compareElementsAt(top, top+2) {
 			// e0 < e2 < e1
 			return top + 2
 		}
@@ -65,9 +57,9 @@ func (qsr QuickSortRoutine) selectPivot(top int32) int32 {
 		return top
 	}
 	// e1 < e0
-	if compareElementsAt(qsr.data, top+1, top+2, qsr.knownToBeSortedCount, qsr.comparisonChannel) {
+	if qsr.compareElementsAt(top+1, top+2) {
 		// e1 < e0 && e1 < e2
-		if compareElementsAt(qsr.data, top, top+2, qsr.knownToBeSortedCount, qsr.comparisonChannel) {
+		if qsr.compareElementsAt(top, top+2) {
 			// e1 < e0 < e2
 			return top
 		}
@@ -83,8 +75,8 @@ func (qsr QuickSortRoutine) insertionSort(rangeToSort sortRange) {
 	for bottom < rangeToSort.bottom {
 		var scanPos int32
 		for scanPos = bottom + 1; scanPos > rangeToSort.top; scanPos = scanPos - 1 {
-			if compareElementsAt(qsr.data, scanPos, scanPos-1, qsr.knownToBeSortedCount, qsr.comparisonChannel) {
-				swapElementsAt(qsr.data, scanPos, scanPos-1, qsr.knownToBeSortedCount, qsr.swapChannel)
+			if qsr.compareElementsAt(scanPos, scanPos-1) {
+				qsr.swapElementsAt(scanPos, scanPos-1)
 			}
 		}
 		bottom = bottom + 1
@@ -100,10 +92,13 @@ func (qsr QuickSortRoutine) insertionSort(rangeToSort sortRange) {
  * select a pivot by considering the first three elements in the list and choosing the
  * middle-sized element
  */
-func (qsr QuickSortRoutine) run() {
+func (qsr QuickSortRoutine) run(ctx context.Context) {
 	var rangesToSort []sortRange = make([]sortRange, 0)
 	rangesToSort = append(rangesToSort, sortRange{0, int32(len(qsr.data) - 1)})
 	for len(rangesToSort) > 0 {
+		if ctx.Err() != nil {
+			return
+		}
 		// pop the next range to sort
 		var rangeToSort = rangesToSort[0]
 		rangesToSort = rangesToSort[1:]
@@ -112,26 +107,26 @@ func (qsr QuickSortRoutine) run() {
 		} else {
 			var pivotPos int32 = qsr.selectPivot(rangeToSort.top)
 			if pivotPos != rangeToSort.top {
-				swapElementsAt(qsr.data, pivotPos, rangeToSort.top, qsr.knownToBeSortedCount, qsr.swapChannel)
+				qsr.swapElementsAt(pivotPos, rangeToSort.top)
 				pivotPos = rangeToSort.top
 			}
 			var scanFromTop int32 = rangeToSort.top + 1
 			var scanFromBottom int32 = rangeToSort.bottom
 			var anySwapWasMade bool = false
 			for scanFromTop < scanFromBottom {
-				for scanFromTop < scanFromBottom && compareElementsAt(qsr.data, scanFromTop, pivotPos, qsr.knownToBeSortedCount, qsr.comparisonChannel) {
+				for scanFromTop < scanFromBottom && qsr.compareElementsAt(scanFromTop, pivotPos) {
 					scanFromTop = scanFromTop + 1
 				}
 				if scanFromTop < scanFromBottom && anySwapWasMade {
 					// we know the element at scanFromBottom is >= pivot element if a swap has occurred in this range - no comparison needed
 					scanFromBottom = scanFromBottom - 1
 				}
-				for scanFromTop < scanFromBottom && compareElementsAt(qsr.data, pivotPos, scanFromBottom, qsr.knownToBeSortedCount, qsr.comparisonChannel) {
+				for scanFromTop < scanFromBottom && qsr.compareElementsAt(pivotPos, scanFromBottom) {
 					scanFromBottom = scanFromBottom - 1
 				}
 				if scanFromTop < scanFromBottom {
 					// both incorrectly positioned elements found, so swap them
-					swapElementsAt(qsr.data, scanFromTop, scanFromBottom, qsr.knownToBeSortedCount, qsr.swapChannel)
+					qsr.swapElementsAt(scanFromTop, scanFromBottom)
 					anySwapWasMade = true
 					scanFromTop = scanFromTop + 1
 				}
@@ -140,11 +135,11 @@ func (qsr QuickSortRoutine) run() {
 			// we know from the selection of pivot approach that at least one element smaller and
 			// one element larger than the pivot exists in rangeToBeSorted
 			// so at the end of partitioning scanFromTop will have moved at least one step past rangeToSort.top
-			swapElementsAt(qsr.data, pivotPos, scanFromTop-1, qsr.knownToBeSortedCount, qsr.swapChannel)
+			qsr.swapElementsAt(pivotPos, scanFromTop-1)
 			qsr.knownToBeSortedCount = qsr.knownToBeSortedCount + 1                                 // pivot element is in its final position
 			rangesToSort = append([]sortRange{{scanFromTop, rangeToSort.bottom}}, rangesToSort...)  // queue larger sublist
 			rangesToSort = append([]sortRange{{rangeToSort.top, scanFromTop - 2}}, rangesToSort...) // queue smaller sublist
 		}
 	}
-	sortingRoutineComplete(qsr.comparisonChannel, qsr.swapChannel)
+	qsr.sortingRoutineComplete()
 }
@@ -0,0 +1,495 @@
+package main
+
+/*
+Parallel Sorting Demo
+Copyright (C) 2020 Robert Sheridan
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const SERVE_DEFAULT_DATA_SIZE int32 = 100
+const SERVE_MAXIMUM_DATA_SIZE int32 = 2000
+const SERVE_KEPT_RACES int = 8
+
+// how long a race may go on, however slowly it is run or however long it is paused, before it is cancelled
+const SERVE_RACE_TIMEOUT time.Duration = 15 * time.Minute
+
+// the number of the latest messages of a race kept for browsers which fall behind, beyond which they are sent a snapshot instead
+const SERVE_KEPT_MESSAGES int = 16384
+
+const SSE_KIND_FINISH string = "finish"
+const SSE_KIND_SNAPSHOT string = "snapshot"
+const SSE_KIND_SUMMARY string = "summary"
+
+//go:embed web/index.html
+var servePage []byte
+
+// serveFinishRecord announces that an algorithm has finished sorting
+type serveFinishRecord struct {
+	Kind      string `json:"kind"`
+	Algorithm string `json:"algorithm"`
+	Position  int    `json:"position"`
+	Error     string `json:"error,omitempty"` // why the algorithm did not sort the data
+}
+
+// serveRaceRecord describes a race which has been started, along with the data every algorithm starts from
+type serveRaceRecord struct {
+	ID   int            `json:"id"`
+	Run  traceRunRecord `json:"run"`
+	Data []int32        `json:"data"`
+}
+
+// serveSnapshotRecord holds the current data of every algorithm of a race, and the algorithms which have finished,
+// for a browser which connects after the race began or falls too far behind to be sent the messages it missed
+type serveSnapshotRecord struct {
+	Kind     string              `json:"kind"`
+	Data     map[string][]int32  `json:"data"` // by algorithm name
+	Finishes []serveFinishRecord `json:"finishes"`
+}
+
+// liveMessage is a message of a race stream, kept as an event until it is sent
+type liveMessage struct {
+	event  traceEvent
+	finish *serveFinishRecord // set instead of event for the announcement that an algorithm has finished
+}
+
+// liveRace is an eventObserver which keeps the latest messages of a race along with the current data of every algorithm,
+// so that a browser which connects after the race began, or falls behind, can be brought up to date
+type liveRace struct {
+	record     serveRaceRecord
+	controller *runController
+	cancel     context.CancelFunc // stops the race early
+	mutex      sync.Mutex
+	changed    *sync.Cond
+	data       map[int][]int32 // the data of each algorithm with every event so far applied
+	finishes   []serveFinishRecord
+	recent     []liveMessage     // the latest messages, between SERVE_KEPT_MESSAGES and twice as many once the race is long enough
+	dropped    int64             // the number of messages before recent which are no longer kept
+	summary    *runSummaryRecord // set once the race is complete
+}
+
+func newLiveRace(id int, header traceHeader, startSlice []int32, controller *runController, cancel context.CancelFunc) *liveRace {
+	race := new(liveRace)
+	race.record = serveRaceRecord{id, runRecord(header), startSlice}
+	race.controller = controller
+	race.cancel = cancel
+	race.changed = sync.NewCond(&race.mutex)
+	race.data = map[int][]int32{}
+	for _, algorithm := range header.algorithms {
+		race.data[algorithm] = make([]int32, len(startSlice))
+		_ = copy(race.data[algorithm], startSlice)
+	}
+	return race
+}
+
+// add keeps a message, applying its event to the data of its algorithm, and wakes every waiting subscriber
+func (race *liveRace) add(message liveMessage) {
+	race.mutex.Lock()
+	var data []int32 = race.data[message.event.algorithm]
+	switch {
+	case message.finish != nil:
+		race.finishes = append(race.finishes, *message.finish)
+	case message.event.kind == TRACE_EVENT_SWAP:
+		data[message.event.swap.index[0]] = message.event.swap.value[1]
+		data[message.event.swap.index[1]] = message.event.swap.value[0]
+	case message.event.kind == TRACE_EVENT_WRITE && !message.event.write.toAuxiliary:
+		data[message.event.write.index[0]] = message.event.write.value[1]
+	}
+	race.recent = append(race.recent, message)
+	if len(race.recent) >= 2*SERVE_KEPT_MESSAGES {
+		// forget the older half at once, so each message is moved at most once
+		var forgotten int = len(race.recent) - SERVE_KEPT_MESSAGES
+		race.recent = race.recent[:copy(race.recent, race.recent[forgotten:])]
+		race.dropped = race.dropped + int64(forgotten)
+	}
+	race.mutex.Unlock()
+	race.changed.Broadcast()
+}
+
+func (race *liveRace) observeComparison(algorithm int, ce ComparisonEvent) {
+	race.add(liveMessage{event: traceEvent{algorithm: algorithm, kind: TRACE_EVENT_COMPARISON, comparison: ce}})
+}
+
+func (race *liveRace) observeSwap(algorithm int, se SwapEvent) {
+	race.add(liveMessage{event: traceEvent{algorithm: algorithm, kind: TRACE_EVENT_SWAP, swap: se}})
+}
+
+func (race *liveRace) observeWrite(algorithm int, we WriteEvent) {
+	race.add(liveMessage{event: traceEvent{algorithm: algorithm, kind: TRACE_EVENT_WRITE, write: we}})
+}
+
+func (race *liveRace) observeLifecycle(event lifecycleEvent) {
+	if event.kind != LIFECYCLE_ALGORITHM_FINISHED {
+		return
+	}
+	var record serveFinishRecord = serveFinishRecord{SSE_KIND_FINISH, algorithmName[event.algorithm], event.finishPosition, ""}
+	if event.err != nil {
+		record.Error = event.err.Error()
+	}
+	race.add(liveMessage{event: traceEvent{algorithm: event.algorithm}, finish: &record})
+}
+
+// run races the algorithms until ctx is done and finishes the stream with the summary of the results
+func (race *liveRace) run(ctx context.Context, options runOptions) {
+	defer race.cancel()
+	var results []*algorithmResult = runSortRace(ctx, race.record.Data, options, race)
+	var summary runSummaryRecord = runSummary{options.data, results}.record()
+	race.mutex.Lock()
+	race.summary = &summary
+	race.mutex.Unlock()
+	race.changed.Broadcast()
+}
+
+// liveRaceUpdate is what a subscriber of a race has not been sent yet
+type liveRaceUpdate struct {
+	snapshot *serveSnapshotRecord // the state of the race, sent in place of messages which are no longer kept
+	messages []liveMessage
+	summary  *runSummaryRecord // set once the race is complete, which ends the stream
+}
+
+// wake makes every subscriber check again whether it should stop waiting
+func (race *liveRace) wake() {
+	// holding the mutex ensures no subscriber is between checking and waiting, where it would miss the broadcast
+	race.mutex.Lock()
+	race.mutex.Unlock()
+	race.changed.Broadcast()
+}
+
+// updateFrom waits until there is more to the race than its first sent messages, or ctx is done,
+// returning what comes after them and the number of messages it brings the subscriber to
+// (a subscriber whose ctx is done should stop rather than use the update, which may be empty)
+func (race *liveRace) updateFrom(ctx context.Context, sent int64) (liveRaceUpdate, int64) {
+	race.mutex.Lock()
+	defer race.mutex.Unlock()
+	var end int64 = race.dropped + int64(len(race.recent))
+	for sent >= end && race.summary == nil && ctx.Err() == nil {
+		race.changed.Wait()
+		end = race.dropped + int64(len(race.recent))
+	}
+	var update liveRaceUpdate
+	if sent < race.dropped {
+		update.snapshot = race.snapshot()
+		sent = end
+	}
+	// copied, as recent is rearranged once the mutex is released
+	update.messages = append([]liveMessage(nil), race.recent[sent-race.dropped:]...)
+	update.summary = race.summary
+	return update, end
+}
+
+// snapshot copies the current state of the race - called with the mutex held
+func (race *liveRace) snapshot() *serveSnapshotRecord {
+	var snapshot *serveSnapshotRecord = &serveSnapshotRecord{SSE_KIND_SNAPSHOT, map[string][]int32{}, append([]serveFinishRecord{}, race.finishes...)}
+	for algorithm, data := range race.data {
+		snapshot.Data[algorithmName[algorithm]] = append([]int32{}, data...)
+	}
+	return snapshot
+}
+
+// serverSentEvent formats a Server-Sent Events message whose event type is kind and whose data is record as JSON
+func serverSentEvent(kind string, record interface{}) []byte {
+	data, err := json.Marshal(record)
+	if err != nil {
+		data, _ = json.Marshal(err.Error())
+		kind = "error"
+	}
+	return []byte(fmt.Sprintf("event: %s\ndata: %s\n\n", kind, data))
+}
+
+func (message liveMessage) serverSentEvent() []byte {
+	if message.finish != nil {
+		return serverSentEvent(SSE_KIND_FINISH, *message.finish)
+	}
+	switch message.event.kind {
+	case TRACE_EVENT_COMPARISON:
+		return serverSentEvent(TRACE_KIND_COMPARISON, comparisonRecord(message.event.algorithm, message.event.comparison))
+	case TRACE_EVENT_SWAP:
+		return serverSentEvent(TRACE_KIND_SWAP, swapRecord(message.event.algorithm, message.event.swap))
+	}
+	return serverSentEvent(TRACE_KIND_WRITE, writeRecord(message.event.algorithm, message.event.write))
+}
+
+// raceServer starts races on request and streams their events to browsers
+type raceServer struct {
+	mutex  sync.Mutex
+	races  map[int]*liveRace
+	nextID int
+}
+
+func newRaceServer() *raceServer {
+	return &raceServer{races: map[int]*liveRace{}, nextID: 1}
+}
+
+func (server *raceServer) handler() http.Handler {
+	var mux *http.ServeMux = http.NewServeMux()
+	mux.HandleFunc("GET /{$}", server.servePage)
+	mux.HandleFunc("GET /algorithms", server.serveChoices)
+	mux.HandleFunc("POST /races", server.startRace)
+	mux.HandleFunc("GET /races/{id}/events", server.streamRace)
+	mux.HandleFunc("GET /races/{id}/control", server.controlRace(describeRun))
+	mux.HandleFunc("POST /races/{id}/pause", server.controlRace(pauseRun))
+	mux.HandleFunc("POST /races/{id}/resume", server.controlRace(resumeRun))
+	mux.HandleFunc("POST /races/{id}/step", server.controlRace(stepRun))
+	mux.HandleFunc("POST /races/{id}/speed", server.controlRace(changeRunSpeed))
+	return mux
+}
+
+func (server *raceServer) servePage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(servePage)
+}
+
+// serveChoices lists the algorithms and data distributions a race can be started with
+func (server *raceServer) serveChoices(w http.ResponseWriter, r *http.Request) {
+	type choice struct {
+		Name        string `json:"name"`
+		Description string `json:"description,omitempty"`
+		Default     bool   `json:"default,omitempty"`
+	}
+	var choices struct {
+		Algorithms    []choice `json:"algorithms"`
+		Distributions []choice `json:"distributions"`
+	}
+	for _, algorithm := range registeredAlgorithms() {
+		choices.Algorithms = append(choices.Algorithms, choice{shortAlgorithmName(algorithm), algorithmName[algorithm], sortAlgorithmRegistry[algorithm].includedByDefault})
+	}
+	for _, distribution := range dataDistributions {
+		choices.Distributions = append(choices.Distributions, choice{distribution.name, distribution.description, distribution.name == DISTRIBUTION_RANDOM})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(choices)
+}
+
+// parseSpeed reads a number of events per second, where 0 (or nothing) means full speed
+func parseSpeed(value string) (float64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	eventsPerSecond, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("eventsPerSecond must be a number")
+	}
+	if err := checkSpeed(eventsPerSecond); err != nil {
+		return 0, err
+	}
+	return eventsPerSecond, nil
+}
+
+// parseRaceRequest reads the settings of a race from the form values algorithms, size, seed, distribution and inversions,
+// and how it starts from eventsPerSecond, paused and lockstep
+func parseRaceRequest(r *http.Request) (runOptions, error) {
+	var options runOptions
+	algorithms, err := parseAlgorithmList(r.FormValue("algorithms"))
+	if err != nil {
+		return options, err
+	}
+	options.algorithms = algorithms
+	options.data = dataSettings{DISTRIBUTION_RANDOM, SERVE_DEFAULT_DATA_SIZE, time.Now().UnixNano(), 10}
+	if value := r.FormValue("size"); value != "" {
+		size, err := strconv.ParseInt(value, 10, 32)
+		if err != nil || size < 0 || int32(size) > SERVE_MAXIMUM_DATA_SIZE {
+			return options, fmt.Errorf("size must be between 0 and %d", SERVE_MAXIMUM_DATA_SIZE)
+		}
+		options.data.size = int32(size)
+	}
+	if value := r.FormValue("seed"); value != "" {
+		seed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return options, fmt.Errorf("seed must be a whole number")
+		}
+		options.data.seed = seed
+	}
+	if value := r.FormValue("distribution"); value != "" {
+		if _, found := findDataDistribution(value); !found {
+			return options, fmt.Errorf("unknown distribution %q - available distributions are: %s", value, dataDistributionNames())
+		}
+		options.data.distribution = value
+	}
+	if value := r.FormValue("inversions"); value != "" {
+		inversions, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || inversions < 0 {
+			return options, fmt.Errorf("inversions must not be negative")
+		}
+		options.data.inversions = inversions
+	}
+	eventsPerSecond, err := parseSpeed(r.FormValue("eventsPerSecond"))
+	if err != nil {
+		return options, err
+	}
+	options.controller = newRunController()
+	options.controller.setSpeed(eventsPerSecond)
+	if r.FormValue("paused") == "true" {
+		options.controller.pause()
+	}
+	if r.FormValue("lockstep") == "true" {
+		options.scheduler = newLockstepScheduler(1, 1, 1)
+	}
+	options.output = OUTPUT_FORMAT_JSON
+	options.reportPeriodStep = 1
+	options.quiet = true
+	return options, nil
+}
+
+// startRace begins a race and replies with its id and starting data, forgetting the oldest races beyond SERVE_KEPT_RACES
+func (server *raceServer) startRace(w http.ResponseWriter, r *http.Request) {
+	options, err := parseRaceRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var startSlice []int32 = makeDataArray(options.data)
+	ctx, cancel := context.WithTimeout(context.Background(), SERVE_RACE_TIMEOUT)
+	server.mutex.Lock()
+	var race *liveRace = newLiveRace(server.nextID, traceHeader{options.data, options.algorithms}, startSlice, options.controller, cancel)
+	server.races[server.nextID] = race
+	if forgotten := server.races[server.nextID-SERVE_KEPT_RACES]; forgotten != nil {
+		// nobody can control or watch a forgotten race any more, so stop it
+		forgotten.cancel()
+		delete(server.races, server.nextID-SERVE_KEPT_RACES)
+	}
+	server.nextID = server.nextID + 1
+	server.mutex.Unlock()
+	go race.run(ctx, options)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(race.record)
+}
+
+func describeRun(controller *runController, r *http.Request) error {
+	return nil
+}
+
+func pauseRun(controller *runController, r *http.Request) error {
+	controller.pause()
+	return nil
+}
+
+func resumeRun(controller *runController, r *http.Request) error {
+	controller.resume()
+	return nil
+}
+
+// stepRun lets each routine emit the number of events in the form value events (default 1) and then pause
+func stepRun(controller *runController, r *http.Request) error {
+	var events int = 1
+	if value := r.FormValue("events"); value != "" {
+		var err error
+		if events, err = strconv.Atoi(value); err != nil || events < 1 {
+			return fmt.Errorf("events must be a whole number of at least 1")
+		}
+	}
+	controller.step(events)
+	return nil
+}
+
+// changeRunSpeed paces each routine to the form value eventsPerSecond
+func changeRunSpeed(controller *runController, r *http.Request) error {
+	eventsPerSecond, err := parseSpeed(r.FormValue("eventsPerSecond"))
+	if err != nil {
+		return err
+	}
+	controller.setSpeed(eventsPerSecond)
+	return nil
+}
+
+// findRace looks up the race named by the id in the path, or returns nil if it is unknown or forgotten
+func (server *raceServer) findRace(r *http.Request) *liveRace {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return nil
+	}
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	return server.races[id]
+}
+
+// controlRace makes a handler which applies change to the controller of a race and replies with the controller's state
+func (server *raceServer) controlRace(change func(controller *runController, r *http.Request) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var race *liveRace = server.findRace(r)
+		if race == nil {
+			http.NotFound(w, r)
+			return
+		}
+		if err := change(race.controller, r); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(race.controller.state())
+	}
+}
+
+// streamRace sends the events of a race as Server-Sent Events until the race is complete or the browser goes away
+// the stream begins with the race and, if the race has gone on too long for its start to be kept, a snapshot of its current state
+func (server *raceServer) streamRace(w http.ResponseWriter, r *http.Request) {
+	var race *liveRace = server.findRace(r)
+	if race == nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	var controller *http.ResponseController = http.NewResponseController(w)
+	if _, err := w.Write(serverSentEvent(TRACE_KIND_RUN, race.record)); err != nil || controller.Flush() != nil {
+		return
+	}
+	// a browser may go away while the race is paused, and must not leave the handler waiting for the race to resume
+	defer context.AfterFunc(r.Context(), race.wake)()
+	var sent int64 = 0
+	for {
+		update, next := race.updateFrom(r.Context(), sent)
+		if r.Context().Err() != nil {
+			return
+		}
+		if update.snapshot != nil {
+			if _, err := w.Write(serverSentEvent(SSE_KIND_SNAPSHOT, *update.snapshot)); err != nil {
+				return
+			}
+		}
+		for _, message := range update.messages {
+			if _, err := w.Write(message.serverSentEvent()); err != nil {
+				return
+			}
+		}
+		sent = next
+		if update.summary != nil {
+			w.Write(serverSentEvent(SSE_KIND_SUMMARY, *update.summary))
+		}
+		if err := controller.Flush(); err != nil || update.summary != nil {
+			return
+		}
+	}
+}
+
+// serveRaces runs the web server until it fails
+func serveRaces(options runOptions) {
+	fmt.Fprintf(os.Stdout, "serving races at http://%s/\n", options.listenAddress)
+	if err := http.ListenAndServe(options.listenAddress, newRaceServer().handler()); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
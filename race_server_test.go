@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newRaceServerForTest makes a race server whose kept races are cancelled and waited for when the test ends,
+// so that none of them is still starting its routines while a later test changes the algorithm registry
+func newRaceServerForTest(t *testing.T) *raceServer {
+	var races *raceServer = newRaceServer()
+	t.Cleanup(func() {
+		races.mutex.Lock()
+		var kept []*liveRace
+		for _, race := range races.races {
+			kept = append(kept, race)
+		}
+		races.mutex.Unlock()
+		for _, race := range kept {
+			race.cancel()
+			var update liveRaceUpdate
+			var sent int64
+			for update.summary == nil {
+				update, sent = race.updateFrom(context.Background(), sent)
+			}
+		}
+	})
+	return races
+}
+
+func TestRaceServerStreamsRaceFromTheStart(t *testing.T) {
+	var server *httptest.Server = httptest.NewServer(newRaceServerForTest(t).handler())
+	defer server.Close()
+	response, err := http.PostForm(server.URL+"/races", url.Values{"algorithms": {"heap,merge"}, "size": {"30"}, "seed": {"5"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var race serveRaceRecord
+	if err := json.NewDecoder(response.Body).Decode(&race); err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusCreated || len(race.Data) != 30 {
+		t.Fatalf("starting a race replied %d with %d elements", response.StatusCode, len(race.Data))
+	}
+	response, err = http.Get(server.URL + "/races/1/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream, err := io.ReadAll(response.Body)
+	response.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, kind := range []string{TRACE_KIND_RUN, TRACE_KIND_COMPARISON, SSE_KIND_FINISH, SSE_KIND_SUMMARY} {
+		if !strings.Contains(string(stream), "event: "+kind+"\n") {
+			t.Errorf("stream has no %s event", kind)
+		}
+	}
+	if !strings.HasPrefix(string(stream), "event: "+TRACE_KIND_RUN+"\n") {
+		t.Error("stream does not begin with the run")
+	}
+	response, err = http.PostForm(server.URL+"/races", url.Values{"algorithms": {"nonsense"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("starting a race with an unknown algorithm replied %d", response.StatusCode)
+	}
+}
+
+func TestLiveRaceSendsSnapshotOnceItsStartIsForgotten(t *testing.T) {
+	var options runOptions = raceOptionsForTest([]int{ALGORITHM_HEAP_SORT, ALGORITHM_INSERTION_SORT}, 400, 9)
+	var startSlice []int32 = makeDataArray(options.data)
+	var race *liveRace = newLiveRace(1, traceHeader{options.data, options.algorithms}, startSlice, newRunController(), func() {})
+	race.run(context.Background(), options)
+	update, sent := race.updateFrom(context.Background(), 0)
+	if update.snapshot == nil || update.summary == nil {
+		t.Fatalf("a late subscriber to a long race was not sent a snapshot and the summary")
+	}
+	if len(race.recent) > 2*SERVE_KEPT_MESSAGES || len(update.messages) != 0 || sent != race.dropped+int64(len(race.recent)) {
+		t.Errorf("kept %d messages and sent %d of them, up to message %d", len(race.recent), len(update.messages), sent)
+	}
+	for name, data := range update.snapshot.Data {
+		if firstOutOfOrderPosition(data) >= 0 {
+			t.Errorf("the snapshot of %s is not sorted: %v", name, data)
+		}
+	}
+	if len(update.snapshot.Finishes) != len(options.algorithms) {
+		t.Errorf("the snapshot holds %d finishes, expected %d", len(update.snapshot.Finishes), len(options.algorithms))
+	}
+}
+
+func TestStreamOfPausedRaceEndsWhenBrowserGoesAway(t *testing.T) {
+	// not closed when the test ends, as closing waits for the stream which may never end
+	var server *httptest.Server = httptest.NewServer(newRaceServerForTest(t).handler())
+	response, err := http.PostForm(server.URL+"/races", url.Values{"algorithms": {"bubble"}, "size": {"30"}, "paused": {"true"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	request, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/races/1/events", nil)
+	response, err = http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	response.Body.Close()
+	// closing the server waits for every handler to return, so this hangs if the stream still waits on the paused race
+	var closed chan bool = make(chan bool)
+	go func() {
+		server.Close()
+		closed <- true
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the stream of a paused race is still waiting after the browser went away")
+	}
+}
+
+func TestForgottenRaceIsCancelled(t *testing.T) {
+	var races *raceServer = newRaceServerForTest(t)
+	var server *httptest.Server = httptest.NewServer(races.handler())
+	defer server.Close()
+	var first *liveRace
+	for n := 0; n <= SERVE_KEPT_RACES; n = n + 1 {
+		response, err := http.PostForm(server.URL+"/races", url.Values{"algorithms": {"bubble"}, "size": {"30"}, "paused": {"true"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		response.Body.Close()
+		if first == nil {
+			first = races.races[1]
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	update, _ := first.updateFrom(ctx, 0)
+	if update.summary == nil || !update.summary.Results[0].Cancelled {
+		t.Errorf("the forgotten paused race was not cancelled: %+v", update.summary)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+/*
+Parallel Sorting Demo
+Copyright (C) 2020 Robert Sheridan
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"context"
+	"encoding/binary"
+	"hash/fnv"
+	"math/rand"
+)
+
+// RandomSortRoutine - sorts by shuffling the list until it happens to be in order, giving up after a limited number of shuffles
+type RandomSortRoutine struct {
+	sortRoutineBase
+	maxAttempts     int32
+	randomGenerator *rand.Rand
+}
+
+// NewRandomSortRoutine factory
+func NewRandomSortRoutine(startSlice []int32) *RandomSortRoutine {
+	return NewRandomSortRoutineWithAttemptLimit(startSlice, RANDOM_SORT_DEFAULT_MAX_ATTEMPTS)
+}
+
+// NewRandomSortRoutineWithAttemptLimit factory (the routine gives up after maxAttempts shuffles)
+func NewRandomSortRoutineWithAttemptLimit(startSlice []int32, maxAttempts int32) *RandomSortRoutine {
+	rsr := new(RandomSortRoutine)
+	rsr.sortRoutineBase = newSortRoutineBase(startSlice)
+	rsr.maxAttempts = maxAttempts
+	rsr.randomGenerator = rand.New(rand.NewSource(seedFromData(startSlice)))
+	return rsr
+}
+
+func init() {
+	registerSortAlgorithm(ALGORITHM_RANDOM_SORT, registeredSortAlgorithm{
+		factory:           func(startSlice []int32) SortRoutine { return NewRandomSortRoutine(startSlice) },
+		includedByDefault: false,
+		sortedRegion:      SORTED_REGION_SCATTERED,
+	})
+}
+
+// seedFromData derives the shuffle seed from the data, so a run repeated with the same data seed shuffles identically
+func seedFromData(data []int32) int64 {
+	var h = fnv.New64a()
+	var buffer [4]byte
+	for _, value := range data {
+		binary.LittleEndian.PutUint32(buffer[:], uint32(value))
+		_, _ = h.Write(buffer[:])
+	}
+	return int64(h.Sum64())
+}
+
+// check each neighboring pair of elements, stopping at the first pair found out of order
+func (rsr RandomSortRoutine) isSorted() bool {
+	var pos int32
+	for pos = 0; pos < rsr.dataSize-1; pos = pos + 1 {
+		if rsr.compareElementsAt(pos+1, pos) {
+			return false
+		}
+	}
+	return true
+}
+
+// shuffle every element into a uniformly random position
+func (rsr RandomSortRoutine) shuffle() {
+	var pos int32
+	for pos = rsr.dataSize - 1; pos > 0; pos = pos - 1 {
+		var pos2 int32 = rsr.randomGenerator.Int31n(pos + 1)
+		if pos2 != pos {
+			rsr.swapElementsAt(pos, pos2)
+		}
+	}
+}
+
+/* Random Sort (bogosort)
+ * check whether the list is sorted and if not, shuffle the whole list and check again
+ * no element is known to be in its final position until the whole list is found sorted
+ * after maxAttempts shuffles without success, give up and signal that sorting was abandoned
+ */
+func (rsr RandomSortRoutine) run(ctx context.Context) {
+	var attempts int32 = 0
+	for !rsr.isSorted() {
+		if ctx.Err() != nil {
+			return
+		}
+		if attempts >= rsr.maxAttempts {
+			rsr.sortingRoutineAbandoned()
+			return
+		}
+		rsr.shuffle()
+		attempts = attempts + 1
+	}
+	rsr.sortingRoutineComplete()
+}
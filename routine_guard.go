@@ -0,0 +1,100 @@
+package main
+
+/*
+Parallel Sorting Demo
+Copyright (C) 2020 Robert Sheridan
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"runtime/debug"
+)
+
+// the number of events each routine remembers for reporting if it panics
+const RECENT_EVENTS_KEPT int = 10
+
+// recentEvents remembers the latest events emitted by a routine, overwriting the oldest
+type recentEvents struct {
+	events [RECENT_EVENTS_KEPT]traceEvent
+	count  int64 // the number of events ever added
+}
+
+func (recent *recentEvents) add(event traceEvent) {
+	recent.events[recent.count%int64(RECENT_EVENTS_KEPT)] = event
+	recent.count = recent.count + 1
+}
+
+// list returns the remembered events, oldest first
+func (recent *recentEvents) list() []traceEvent {
+	var kept int64 = recent.count
+	if kept > int64(RECENT_EVENTS_KEPT) {
+		kept = int64(RECENT_EVENTS_KEPT)
+	}
+	var events []traceEvent = make([]traceEvent, 0, kept)
+	for n := recent.count - kept; n < recent.count; n = n + 1 {
+		events = append(events, recent.events[n%int64(RECENT_EVENTS_KEPT)])
+	}
+	return events
+}
+
+// routinePanic is the error of a routine which panicked, with what is known about where it was
+type routinePanic struct {
+	value      interface{} // the value passed to panic
+	stack      []byte
+	lastEvents []traceEvent // the latest events emitted before the panic, oldest first
+}
+
+func (crash *routinePanic) Error() string {
+	return fmt.Sprintf("panicked: %v", crash.value)
+}
+
+func describeTraceEvent(event traceEvent) string {
+	switch event.kind {
+	case TRACE_EVENT_COMPARISON:
+		return fmt.Sprintf("%d comparison of positions %v with values %v (first was lower: %t)", event.comparison.sequence, event.comparison.index, event.comparison.value, event.comparison.firstWasLower)
+	case TRACE_EVENT_SWAP:
+		return fmt.Sprintf("%d swap of positions %v with values %v", event.swap.sequence, event.swap.index, event.swap.value)
+	}
+	var direction string = "from auxiliary"
+	if event.write.toAuxiliary {
+		direction = "to auxiliary"
+	}
+	return fmt.Sprintf("%d write %s of positions %v with values %v", event.write.sequence, direction, event.write.index, event.write.value)
+}
+
+// report describes the panic of algorithm in full, with the events leading up to it and the stack
+func (crash *routinePanic) report(w io.Writer, algorithm int) {
+	fmt.Fprintf(w, "algorithm %s %s\n", algorithmName[algorithm], crash.Error())
+	fmt.Fprintf(w, "last %d events emitted:\n", len(crash.lastEvents))
+	for _, event := range crash.lastEvents {
+		fmt.Fprintf(w, "  %s\n", describeTraceEvent(event))
+	}
+	fmt.Fprintf(w, "%s\n", crash.stack)
+}
+
+// runGuarded runs routine, recovering from a panic so that the other routines of the race can finish
+// it returns the panic, or nil if the routine returned normally
+func runGuarded(ctx context.Context, routine SortRoutine) (crash *routinePanic) {
+	defer func() {
+		if value := recover(); value != nil {
+			crash = &routinePanic{value, debug.Stack(), routine.getControl().recentEvents.list()}
+		}
+	}()
+	routine.run(ctx)
+	return nil
+}
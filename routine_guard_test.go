@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// panickingSortRoutine compares a few elements and then reads past the end of the data
+type panickingSortRoutine struct {
+	sortRoutineBase
+}
+
+func (psr panickingSortRoutine) run(ctx context.Context) {
+	var pos int32
+	for pos = 0; pos < 3; pos = pos + 1 {
+		psr.compareElementsAt(pos, pos+1)
+	}
+	psr.compareElementsAt(0, psr.dataSize)
+}
+
+// racePanickingRoutine races a panicking routine in place of random sort against quick sort, in lockstep if scheduler is not nil
+func racePanickingRoutine(t *testing.T, scheduler *lockstepScheduler) []*algorithmResult {
+	var registered registeredSortAlgorithm = sortAlgorithmRegistry[ALGORITHM_RANDOM_SORT]
+	t.Cleanup(func() { sortAlgorithmRegistry[ALGORITHM_RANDOM_SORT] = registered })
+	sortAlgorithmRegistry[ALGORITHM_RANDOM_SORT] = registeredSortAlgorithm{
+		factory:      func(startSlice []int32) SortRoutine { return &panickingSortRoutine{newSortRoutineBase(startSlice)} },
+		sortedRegion: SORTED_REGION_SCATTERED,
+	}
+	var options runOptions = raceOptionsForTest([]int{ALGORITHM_RANDOM_SORT, ALGORITHM_QUICK_SORT}, 200, 5)
+	options.scheduler = scheduler
+	return runSortRace(context.Background(), makeDataArray(options.data), options, eventObservers{})
+}
+
+func TestRaceReportsAPanickingRoutineAsFailed(t *testing.T) {
+	var results []*algorithmResult = racePanickingRoutine(t, nil)
+	var crash *routinePanic = results[0].panicked
+	if crash == nil {
+		t.Fatal("the panic was not recorded")
+	}
+	if results[0].sortedCorrectly || results[0].rank != 0 || !errors.Is(results[0].failure(), crash) {
+		t.Errorf("the panicking routine was not marked as failed: %+v", *results[0])
+	}
+	if len(crash.lastEvents) != 3 || crash.lastEvents[2].comparison.index != [2]int32{2, 3} {
+		t.Errorf("unexpected last events %v", crash.lastEvents)
+	}
+	if !strings.Contains(string(crash.stack), "panickingSortRoutine") {
+		t.Errorf("the stack does not show the panicking routine:\n%s", crash.stack)
+	}
+	if !results[1].sortedCorrectly || results[1].rank != 1 {
+		t.Errorf("quick sort did not finish: %+v", *results[1])
+	}
+}
+
+func TestLockstepRaceContinuesPastAPanickingRoutine(t *testing.T) {
+	var results []*algorithmResult = racePanickingRoutine(t, newLockstepScheduler(1, 1, 1))
+	if results[0].panicked == nil || !results[1].sortedCorrectly {
+		t.Fatalf("unexpected results %+v and %+v", *results[0], *results[1])
+	}
+}
+
+func TestRecentEventsKeepsTheLatestEvents(t *testing.T) {
+	var recent recentEvents
+	var sequence int64
+	for sequence = 1; sequence <= int64(RECENT_EVENTS_KEPT)+5; sequence = sequence + 1 {
+		recent.add(traceEvent{kind: TRACE_EVENT_SWAP, swap: SwapEvent{sequence: sequence}})
+	}
+	var events []traceEvent = recent.list()
+	if len(events) != RECENT_EVENTS_KEPT || events[0].sequence() != 6 || events[len(events)-1].sequence() != sequence-1 {
+		t.Fatalf("unexpected recent events %v", events)
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+/*
+Parallel Sorting Demo
+Copyright (C) 2020 Robert Sheridan
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// the slowest pace a routine may be set to, so that no wait between two events is longer than 100 seconds
+const MINIMUM_EVENTS_PER_SECOND float64 = 0.01
+
+// runController lets every routine of a race be paused, stepped a few events at a time, slowed down and resumed
+// routines check with it before each event they emit, so a paused routine stops just before its next comparison, swap or write
+type runController struct {
+	mutex           sync.Mutex
+	changed         *sync.Cond
+	paused          bool
+	stepRound       int64 // increased by each step, so that every routine gets stepsPerRound more events
+	stepsPerRound   int
+	eventsPerSecond float64 // the pace of each routine, or 0 to run at full speed
+}
+
+// routineControl holds what holds a routine back: a context stopping it, a controller pausing or pacing it and a scheduler racing it in lockstep
+// (either may be nil), with the state they keep about the routine guarded by their own mutexes
+type routineControl struct {
+	context    context.Context // cancelled when the routine should stop, set up by the race before the routine runs
+	controller *runController
+	stepRound  int64
+	stepsUsed  int
+	scheduler  *lockstepScheduler
+	dueAtTick  int64 // the tick granted to the latest event
+	// the latest events emitted, kept by the routine itself for reporting if it panics
+	recentEvents recentEvents
+}
+
+// runControllerState describes a controller for clients of the control endpoints
+type runControllerState struct {
+	Paused          bool    `json:"paused"`
+	EventsPerSecond float64 `json:"eventsPerSecond"`
+}
+
+func newRunController() *runController {
+	controller := new(runController)
+	controller.changed = sync.NewCond(&controller.mutex)
+	return controller
+}
+
+func (controller *runController) pause() {
+	controller.mutex.Lock()
+	defer controller.mutex.Unlock()
+	controller.paused = true
+}
+
+func (controller *runController) resume() {
+	controller.mutex.Lock()
+	controller.paused = false
+	controller.mutex.Unlock()
+	controller.changed.Broadcast()
+}
+
+// step lets every routine emit events more events, then pause again
+func (controller *runController) step(events int) {
+	controller.mutex.Lock()
+	controller.paused = true
+	controller.stepRound = controller.stepRound + 1
+	controller.stepsPerRound = events
+	controller.mutex.Unlock()
+	controller.changed.Broadcast()
+}
+
+// setSpeed paces each routine to eventsPerSecond, or lets it run at full speed when eventsPerSecond is 0
+func (controller *runController) setSpeed(eventsPerSecond float64) {
+	controller.mutex.Lock()
+	defer controller.mutex.Unlock()
+	controller.eventsPerSecond = eventsPerSecond
+}
+
+func (controller *runController) state() runControllerState {
+	controller.mutex.Lock()
+	defer controller.mutex.Unlock()
+	return runControllerState{controller.paused, controller.eventsPerSecond}
+}
+
+// wake lets waiting routines notice that they have been cancelled
+func (controller *runController) wake() {
+	controller.mutex.Lock()
+	defer controller.mutex.Unlock()
+	controller.changed.Broadcast()
+}
+
+// await blocks the routine until it may emit its next event
+func (controller *runController) await(control *routineControl) {
+	controller.mutex.Lock()
+	for controller.paused && control.context.Err() == nil {
+		if control.stepRound != controller.stepRound {
+			control.stepRound = controller.stepRound
+			control.stepsUsed = 0
+		}
+		if control.stepsUsed < controller.stepsPerRound {
+			control.stepsUsed = control.stepsUsed + 1
+			break
+		}
+		controller.changed.Wait()
+	}
+	var eventsPerSecond float64 = controller.eventsPerSecond
+	controller.mutex.Unlock()
+	if eventsPerSecond > 0 {
+		var pace *time.Timer = time.NewTimer(time.Duration(float64(time.Second) / eventsPerSecond))
+		defer pace.Stop()
+		select {
+		case <-pace.C:
+		case <-control.context.Done():
+		}
+	}
+}
+
+// checkSpeed accepts a pace of 0 events per second, meaning full speed, or one of at least MINIMUM_EVENTS_PER_SECOND
+func checkSpeed(eventsPerSecond float64) error {
+	if math.IsNaN(eventsPerSecond) || math.IsInf(eventsPerSecond, 0) || (eventsPerSecond != 0 && eventsPerSecond < MINIMUM_EVENTS_PER_SECOND) {
+		return fmt.Errorf("events per second must be 0 for full speed, or a number of at least %g", MINIMUM_EVENTS_PER_SECOND)
+	}
+	return nil
+}
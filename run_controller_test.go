@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestRunControllerStepsEachRoutineWhilePaused(t *testing.T) {
+	var controller *runController = newRunController()
+	controller.pause()
+	var options runOptions = raceOptionsForTest([]int{ALGORITHM_QUICK_SORT, ALGORITHM_MERGE_SORT}, 40, 9)
+	options.controller = controller
+	var startSlice []int32 = makeDataArray(options.data)
+	var collector *traceEventCollector = new(traceEventCollector)
+	var finished chan []*algorithmResult = make(chan []*algorithmResult)
+	go func() {
+		finished <- runSortRace(context.Background(), startSlice, options, collector)
+	}()
+	var eventCounts = func() map[int]int {
+		var counts = map[int]int{}
+		for _, event := range collector.collectedEvents() {
+			counts[event.algorithm] = counts[event.algorithm] + 1
+		}
+		return counts
+	}
+	controller.step(3)
+	var deadline time.Time = time.Now().Add(5 * time.Second)
+	for eventCounts()[ALGORITHM_QUICK_SORT] < 3 || eventCounts()[ALGORITHM_MERGE_SORT] < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("routines did not step, observed %v", eventCounts())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	for _, algorithm := range options.algorithms {
+		if count := eventCounts()[algorithm]; count != 3 {
+			t.Errorf("%s emitted %d events after stepping 3 while paused", algorithmName[algorithm], count)
+		}
+	}
+	controller.resume()
+	select {
+	case results := <-finished:
+		for _, result := range results {
+			if !result.sortedCorrectly {
+				t.Errorf("%s did not sort after resuming", algorithmName[result.algorithm])
+			}
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("race did not finish after resuming")
+	}
+}
+
+func TestCancellingInterruptsTheWaitBetweenPacedEvents(t *testing.T) {
+	var options runOptions = raceOptionsForTest([]int{ALGORITHM_QUICK_SORT}, 10, 3)
+	options.controller = newRunController()
+	options.controller.setSpeed(0.2)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	var start time.Time = time.Now()
+	var results []*algorithmResult = runSortRace(ctx, makeDataArray(options.data), options, eventObservers{})
+	if elapsed := time.Since(start); elapsed > 2*time.Second || !results[0].cancelled {
+		t.Errorf("race paced at one event every 5s took %s to be cancelled after 50ms", elapsed)
+	}
+}
+
+func TestCheckSpeedRejectsPacesWhichNeverEnd(t *testing.T) {
+	for _, eventsPerSecond := range []float64{0, MINIMUM_EVENTS_PER_SECOND, 1000} {
+		if err := checkSpeed(eventsPerSecond); err != nil {
+			t.Errorf("%g events per second was rejected: %v", eventsPerSecond, err)
+		}
+	}
+	for _, eventsPerSecond := range []float64{-1, 1e-9, math.NaN(), math.Inf(1)} {
+		if checkSpeed(eventsPerSecond) == nil {
+			t.Errorf("%g events per second was accepted", eventsPerSecond)
+		}
+	}
+}
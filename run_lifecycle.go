@@ -0,0 +1,159 @@
+package main
+
+/*
+Parallel Sorting Demo
+Copyright (C) 2020 Robert Sheridan
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// the kinds of lifecycle event, in the order they happen
+const (
+	LIFECYCLE_RUN_STARTED int = iota + 1
+	LIFECYCLE_ALGORITHM_STARTED
+	LIFECYCLE_ALGORITHM_FINISHED
+	LIFECYCLE_RUN_FINISHED
+)
+
+// the parts of an algorithm's run which must all be done before it has finished: the routine and its three channel processors
+const ALGORITHM_RUN_PARTS int = 4
+
+var errRunFinished = errors.New("the run has already finished")
+
+// lifecycleEvent marks a step in the life of a race
+type lifecycleEvent struct {
+	kind           int              // one of the LIFECYCLE_ values
+	algorithm      int              // the algorithm started or finished, or 0 for the run itself
+	result         *algorithmResult // the totals of the finished algorithm, every event of which has been processed
+	err            error            // why the finished algorithm did not sort the data, or nil if it did
+	finishPosition int              // the order in which the finished algorithm's routine returned, starting at 1
+}
+
+// lifecycleObserver is implemented by eventObservers which also follow the lifecycle of the race
+// lifecycle events are delivered one at a time, in the order they happen
+type lifecycleObserver interface {
+	observeLifecycle(event lifecycleEvent)
+}
+
+// runLifecycle follows every algorithm of a race from its start until its routine has returned and all its events have been processed
+// algorithms may be added until the run has finished, and finish events are delivered in the order the routines returned,
+// so observers see a consistent finish order even when the processing of an algorithm's events lags behind
+type runLifecycle struct {
+	mutex         sync.Mutex
+	changed       *sync.Cond
+	observer      lifecycleObserver // nil if nobody follows the lifecycle
+	unfinished    int
+	returnedCount int
+	nextToDeliver int                         // the finish position whose event is due next
+	awaitingTurn  map[int]*algorithmLifecycle // algorithms which have finished, by finish position, until their events are delivered
+	finishOrder   []*algorithmResult
+	finished      bool
+}
+
+// algorithmLifecycle follows the parts of one algorithm's run
+type algorithmLifecycle struct {
+	lifecycle      *runLifecycle
+	result         *algorithmResult
+	partsRemaining int
+	finishPosition int
+}
+
+// newRunLifecycle starts following a run, telling observer about it if observer follows lifecycles
+func newRunLifecycle(observer eventObserver) *runLifecycle {
+	lifecycle := new(runLifecycle)
+	lifecycle.changed = sync.NewCond(&lifecycle.mutex)
+	lifecycle.awaitingTurn = map[int]*algorithmLifecycle{}
+	lifecycle.nextToDeliver = 1
+	if lo, isLifecycleObserver := observer.(lifecycleObserver); isLifecycleObserver {
+		lifecycle.observer = lo
+	}
+	lifecycle.mutex.Lock()
+	defer lifecycle.mutex.Unlock()
+	lifecycle.deliver(lifecycleEvent{kind: LIFECYCLE_RUN_STARTED})
+	return lifecycle
+}
+
+// deliver passes an event to the observer - called with the mutex held so that events arrive in order
+func (lifecycle *runLifecycle) deliver(event lifecycleEvent) {
+	if lifecycle.observer != nil {
+		lifecycle.observer.observeLifecycle(event)
+	}
+}
+
+// addAlgorithm starts following the run of result's algorithm, which may join a run already under way
+func (lifecycle *runLifecycle) addAlgorithm(result *algorithmResult) (*algorithmLifecycle, error) {
+	lifecycle.mutex.Lock()
+	defer lifecycle.mutex.Unlock()
+	if lifecycle.finished {
+		return nil, fmt.Errorf("cannot add %s: %w", algorithmName[result.algorithm], errRunFinished)
+	}
+	lifecycle.unfinished = lifecycle.unfinished + 1
+	lifecycle.deliver(lifecycleEvent{kind: LIFECYCLE_ALGORITHM_STARTED, algorithm: result.algorithm})
+	return &algorithmLifecycle{lifecycle, result, ALGORITHM_RUN_PARTS, 0}, nil
+}
+
+// routineReturned records that the algorithm's routine will emit no more events, deciding its finish position
+func (al *algorithmLifecycle) routineReturned() {
+	al.lifecycle.mutex.Lock()
+	defer al.lifecycle.mutex.Unlock()
+	al.lifecycle.returnedCount = al.lifecycle.returnedCount + 1
+	al.finishPosition = al.lifecycle.returnedCount
+	al.partDone()
+}
+
+// processingDone records that one of the algorithm's channel processors has finished
+func (al *algorithmLifecycle) processingDone() {
+	al.lifecycle.mutex.Lock()
+	defer al.lifecycle.mutex.Unlock()
+	al.partDone()
+}
+
+// partDone counts off a part of the run, delivering the finish events which are now due - called with the mutex held
+func (al *algorithmLifecycle) partDone() {
+	var lifecycle *runLifecycle = al.lifecycle
+	al.partsRemaining = al.partsRemaining - 1
+	if al.partsRemaining > 0 {
+		return
+	}
+	lifecycle.awaitingTurn[al.finishPosition] = al
+	for next := lifecycle.awaitingTurn[lifecycle.nextToDeliver]; next != nil; next = lifecycle.awaitingTurn[lifecycle.nextToDeliver] {
+		delete(lifecycle.awaitingTurn, lifecycle.nextToDeliver)
+		lifecycle.nextToDeliver = lifecycle.nextToDeliver + 1
+		next.result.finishPosition = next.finishPosition
+		lifecycle.finishOrder = append(lifecycle.finishOrder, next.result)
+		lifecycle.deliver(lifecycleEvent{LIFECYCLE_ALGORITHM_FINISHED, next.result.algorithm, next.result, next.result.failure(), next.finishPosition})
+		lifecycle.unfinished = lifecycle.unfinished - 1
+	}
+	lifecycle.changed.Broadcast()
+}
+
+// wait blocks until every algorithm added so far has finished, then finishes the run and returns the results in finish order
+func (lifecycle *runLifecycle) wait() []*algorithmResult {
+	lifecycle.mutex.Lock()
+	defer lifecycle.mutex.Unlock()
+	for lifecycle.unfinished > 0 {
+		lifecycle.changed.Wait()
+	}
+	if !lifecycle.finished {
+		lifecycle.finished = true
+		lifecycle.deliver(lifecycleEvent{kind: LIFECYCLE_RUN_FINISHED})
+	}
+	return lifecycle.finishOrder
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+// lifecycleRecorder collects every lifecycle event it observes
+type lifecycleRecorder struct {
+	eventObservers // observes no events
+	events         []lifecycleEvent
+}
+
+func (recorder *lifecycleRecorder) observeLifecycle(event lifecycleEvent) {
+	recorder.events = append(recorder.events, event)
+}
+
+func TestRunLifecycleDeliversFinishesInTheOrderRoutinesReturned(t *testing.T) {
+	var recorder *lifecycleRecorder = new(lifecycleRecorder)
+	var lifecycle *runLifecycle = newRunLifecycle(recorder)
+	var algorithms []int = []int{ALGORITHM_BUBBLE_SORT, ALGORITHM_INSERTION_SORT}
+	var runs []*algorithmLifecycle
+	for _, algorithm := range algorithms {
+		var sr SortRoutine = newSortRoutine(algorithm, []int32{1, 2})
+		al, err := lifecycle.addAlgorithm(newAlgorithmResult(algorithm, sr))
+		if err != nil {
+			t.Fatal(err)
+		}
+		runs = append(runs, al)
+	}
+	// insertion sort returns first but bubble sort's events are processed first
+	runs[1].routineReturned()
+	runs[0].routineReturned()
+	for part := 1; part < ALGORITHM_RUN_PARTS; part = part + 1 {
+		runs[0].processingDone()
+	}
+	if len(recorder.events) != 3 {
+		t.Fatalf("bubble sort finished before insertion sort, which returned first: %v", recorder.events)
+	}
+	for part := 1; part < ALGORITHM_RUN_PARTS; part = part + 1 {
+		runs[1].processingDone()
+	}
+	var finishOrder []*algorithmResult = lifecycle.wait()
+	if len(finishOrder) != 2 || finishOrder[0].algorithm != ALGORITHM_INSERTION_SORT || finishOrder[1].algorithm != ALGORITHM_BUBBLE_SORT {
+		t.Fatalf("unexpected finish order %v", finishOrder)
+	}
+	var expectedKinds []int = []int{LIFECYCLE_RUN_STARTED, LIFECYCLE_ALGORITHM_STARTED, LIFECYCLE_ALGORITHM_STARTED, LIFECYCLE_ALGORITHM_FINISHED, LIFECYCLE_ALGORITHM_FINISHED, LIFECYCLE_RUN_FINISHED}
+	if len(recorder.events) != len(expectedKinds) {
+		t.Fatalf("expected %d lifecycle events, got %d", len(expectedKinds), len(recorder.events))
+	}
+	for n, kind := range expectedKinds {
+		if recorder.events[n].kind != kind {
+			t.Errorf("lifecycle event %d is of kind %d, expected %d", n, recorder.events[n].kind, kind)
+		}
+	}
+	if recorder.events[3].algorithm != ALGORITHM_INSERTION_SORT || recorder.events[3].finishPosition != 1 || recorder.events[4].finishPosition != 2 {
+		t.Errorf("finish events out of order: %v", recorder.events[3:5])
+	}
+}
+
+func TestRunLifecycleAcceptsAlgorithmsUntilTheRunFinishes(t *testing.T) {
+	var lifecycle *runLifecycle = newRunLifecycle(eventObservers{})
+	var sr SortRoutine = newSortRoutine(ALGORITHM_BUBBLE_SORT, []int32{2, 1})
+	al, err := lifecycle.addAlgorithm(newAlgorithmResult(ALGORITHM_BUBBLE_SORT, sr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var finished chan []*algorithmResult = make(chan []*algorithmResult)
+	go func() { finished <- lifecycle.wait() }()
+	// an algorithm added while the run is being waited for is waited for too
+	late, err := lifecycle.addAlgorithm(newAlgorithmResult(ALGORITHM_INSERTION_SORT, newSortRoutine(ALGORITHM_INSERTION_SORT, []int32{2, 1})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	al.routineReturned()
+	for part := 1; part < ALGORITHM_RUN_PARTS; part = part + 1 {
+		al.processingDone()
+	}
+	select {
+	case <-finished:
+		t.Fatal("the run finished while an added algorithm was still running")
+	default:
+	}
+	late.routineReturned()
+	for part := 1; part < ALGORITHM_RUN_PARTS; part = part + 1 {
+		late.processingDone()
+	}
+	if finishOrder := <-finished; len(finishOrder) != 2 {
+		t.Fatalf("expected 2 finished algorithms, got %d", len(finishOrder))
+	}
+	_, err = lifecycle.addAlgorithm(newAlgorithmResult(ALGORITHM_BUBBLE_SORT, sr))
+	if !errors.Is(err, errRunFinished) {
+		t.Fatalf("expected adding to a finished run to fail, got %v", err)
+	}
+}
+
+func TestSortRaceReportsFinishEventsToObservers(t *testing.T) {
+	var recorder *lifecycleRecorder = new(lifecycleRecorder)
+	_, results := raceForTest(t, defaultAlgorithms(), 40, 3, recorder)
+	var finishes int = 0
+	for _, event := range recorder.events {
+		if event.kind != LIFECYCLE_ALGORITHM_FINISHED {
+			continue
+		}
+		finishes = finishes + 1
+		if event.finishPosition != finishes || event.result.finishPosition != finishes {
+			t.Errorf("finish event %d has position %d", finishes, event.finishPosition)
+		}
+		if event.err != nil {
+			t.Errorf("%s failed: %v", algorithmName[event.algorithm], event.err)
+		}
+	}
+	if finishes != len(results) {
+		t.Fatalf("expected %d finish events, got %d", len(results), finishes)
+	}
+	if recorder.events[len(recorder.events)-1].kind != LIFECYCLE_RUN_FINISHED {
+		t.Error("the last lifecycle event does not finish the run")
+	}
+}
@@ -0,0 +1,262 @@
+package main
+
+/*
+Parallel Sorting Demo
+Copyright (C) 2020 Robert Sheridan
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// algorithmResult collects the totals of one algorithm's run in a race
+type algorithmResult struct {
+	algorithm       int
+	routine         SortRoutine
+	dataSize        int32
+	comparisons     int64
+	swaps           int64
+	writes          int64
+	elapsed         time.Duration
+	ticks           int64 // the lockstep clock tick of the last event, or 0 when not racing in lockstep
+	gaveUp          bool
+	panicked        *routinePanic // the panic which stopped the routine, or nil if it returned normally
+	cancelled       bool          // whether the routine was stopped by a timeout before it sorted the data
+	sortedFraction  float64       // the proportion of elements in their sorted positions when the routine stopped
+	sortedCorrectly bool
+	rank            int // finishing position among the correctly sorted algorithms, or 0 if not sorted correctly
+	finishPosition  int // the order in which the routine returned among all the algorithms, starting at 1
+}
+
+func newAlgorithmResult(algorithm int, routine SortRoutine) *algorithmResult {
+	result := new(algorithmResult)
+	result.algorithm = algorithm
+	result.routine = routine
+	result.dataSize = routine.getDataSize()
+	return result
+}
+
+// failure explains why the algorithm did not sort the data, or returns nil if it did
+func (result *algorithmResult) failure() error {
+	switch {
+	case result.panicked != nil:
+		return result.panicked
+	case result.sortedCorrectly:
+		return nil
+	case result.gaveUp:
+		return errors.New("gave up before the data was sorted")
+	case result.cancelled:
+		return fmt.Errorf("cancelled at %.0f%% sorted", result.sortedFraction*100)
+	}
+	return errors.New("finished without sorting the data")
+}
+
+func (result *algorithmResult) eventCount() int64 {
+	return result.comparisons + result.swaps + result.writes
+}
+
+func (result *algorithmResult) eventsPerSecond() float64 {
+	if result.elapsed <= 0 {
+		return 0
+	}
+	return float64(result.eventCount()) / result.elapsed.Seconds()
+}
+
+// sortedFraction is the proportion of data whose elements are where they are in sortedData
+func sortedFraction(data []int32, sortedData []int32) float64 {
+	if len(data) == 0 {
+		return 1
+	}
+	var inPlace int = 0
+	for pos := range data {
+		if data[pos] == sortedData[pos] {
+			inPlace = inPlace + 1
+		}
+	}
+	return float64(inPlace) / float64(len(data))
+}
+
+// rankResults numbers the correctly sorted algorithms in order of elapsed time, fastest first,
+// or in order of lockstep ticks (ties keeping the order raced) when the race was in lockstep
+func rankResults(results []*algorithmResult) {
+	var ranked []*algorithmResult = make([]*algorithmResult, 0, len(results))
+	for _, result := range results {
+		result.rank = 0
+		if result.sortedCorrectly {
+			ranked = append(ranked, result)
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].ticks > 0 || ranked[j].ticks > 0 {
+			return ranked[i].ticks < ranked[j].ticks
+		}
+		return ranked[i].elapsed < ranked[j].elapsed
+	})
+	for position, result := range ranked {
+		result.rank = position + 1
+	}
+}
+
+// runSummary describes a whole race, for output as a table, JSON or CSV
+type runSummary struct {
+	data    dataSettings
+	results []*algorithmResult
+}
+
+type algorithmResultRecord struct {
+	Algorithm       string  `json:"algorithm"`
+	Comparisons     int64   `json:"comparisons"`
+	Swaps           int64   `json:"swaps"`
+	Writes          int64   `json:"writes"`
+	ElapsedSeconds  float64 `json:"elapsedSeconds"`
+	EventsPerSecond float64 `json:"eventsPerSecond"`
+	Ticks           int64   `json:"ticks"`
+	GaveUp          bool    `json:"gaveUp"`
+	Cancelled       bool    `json:"cancelled"`
+	SortedFraction  float64 `json:"sortedFraction"`
+	SortedCorrectly bool    `json:"sortedCorrectly"`
+	Rank            int     `json:"rank"`
+	FinishPosition  int     `json:"finishPosition"`
+	Error           string  `json:"error,omitempty"` // why the algorithm did not sort the data
+}
+
+type runSummaryRecord struct {
+	Distribution string                  `json:"distribution"`
+	Size         int32                   `json:"size"`
+	Seed         int64                   `json:"seed"`
+	Results      []algorithmResultRecord `json:"results"`
+}
+
+func (summary runSummary) record() runSummaryRecord {
+	var record runSummaryRecord = runSummaryRecord{summary.data.distribution, summary.data.size, summary.data.seed, make([]algorithmResultRecord, 0, len(summary.results))}
+	for _, result := range summary.results {
+		record.Results = append(record.Results, algorithmResultRecord{
+			algorithmName[result.algorithm],
+			result.comparisons,
+			result.swaps,
+			result.writes,
+			result.elapsed.Seconds(),
+			result.eventsPerSecond(),
+			result.ticks,
+			result.gaveUp,
+			result.cancelled,
+			result.sortedFraction,
+			result.sortedCorrectly,
+			result.rank,
+			result.finishPosition,
+			errorDescription(result.failure()),
+		})
+	}
+	return record
+}
+
+func (summary runSummary) write(w io.Writer, format string) error {
+	switch format {
+	case OUTPUT_FORMAT_JSON:
+		return summary.writeJSON(w)
+	case OUTPUT_FORMAT_CSV:
+		return summary.writeCSV(w)
+	}
+	return summary.writeTable(w)
+}
+
+func errorDescription(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func rankDescription(result *algorithmResult) string {
+	if result.rank > 0 {
+		return strconv.Itoa(result.rank)
+	}
+	if result.gaveUp {
+		return "gave up"
+	}
+	if result.panicked != nil {
+		return "panicked"
+	}
+	if result.cancelled {
+		return fmt.Sprintf("cancelled at %.0f%% sorted", result.sortedFraction*100)
+	}
+	return "failed"
+}
+
+func (summary runSummary) writeTable(w io.Writer) error {
+	fmt.Fprintf(w, "\nsummary of %d elements with distribution %s and seed %d\n", summary.data.size, summary.data.distribution, summary.data.seed)
+	var tw *tabwriter.Writer = tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	var lockstep bool = false
+	for _, result := range summary.results {
+		lockstep = lockstep || result.ticks > 0
+	}
+	var ticksHeading string = ""
+	if lockstep {
+		ticksHeading = "ticks\t"
+	}
+	fmt.Fprintln(tw, "rank\talgorithm\tcomparisons\tswaps\twrites\telapsed\tevents/s\tsorted\t"+ticksHeading)
+	for _, result := range summary.results {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%s\t%.0f\t%t\t", rankDescription(result), algorithmName[result.algorithm], result.comparisons, result.swaps, result.writes, result.elapsed.Round(time.Microsecond), result.eventsPerSecond(), result.sortedCorrectly)
+		if lockstep {
+			fmt.Fprintf(tw, "%d\t", result.ticks)
+		}
+		fmt.Fprintln(tw)
+	}
+	return tw.Flush()
+}
+
+func (summary runSummary) writeJSON(w io.Writer) error {
+	var encoder *json.Encoder = json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(summary.record())
+}
+
+func (summary runSummary) writeCSV(w io.Writer) error {
+	var cw *csv.Writer = csv.NewWriter(w)
+	_ = cw.Write([]string{"distribution", "size", "seed", "algorithm", "comparisons", "swaps", "writes", "elapsed_seconds", "events_per_second", "gave_up", "sorted_correctly", "rank", "ticks", "cancelled", "sorted_fraction", "finish_position", "error"})
+	var record runSummaryRecord = summary.record()
+	for _, result := range record.Results {
+		_ = cw.Write([]string{
+			record.Distribution,
+			strconv.Itoa(int(record.Size)),
+			strconv.FormatInt(record.Seed, 10),
+			result.Algorithm,
+			strconv.FormatInt(result.Comparisons, 10),
+			strconv.FormatInt(result.Swaps, 10),
+			strconv.FormatInt(result.Writes, 10),
+			strconv.FormatFloat(result.ElapsedSeconds, 'f', 6, 64),
+			strconv.FormatFloat(result.EventsPerSecond, 'f', 0, 64),
+			strconv.FormatBool(result.GaveUp),
+			strconv.FormatBool(result.SortedCorrectly),
+			strconv.Itoa(result.Rank),
+			strconv.FormatInt(result.Ticks, 10),
+			strconv.FormatBool(result.Cancelled),
+			strconv.FormatFloat(result.SortedFraction, 'f', 4, 64),
+			strconv.Itoa(result.FinishPosition),
+			result.Error,
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
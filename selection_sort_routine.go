@@ -18,50 +18,44 @@ You should have received a copy of the GNU Affero General Public License
 along with this program.  If not, see <https://www.gnu.org/licenses/>.
 */
 
+import "context"
+
 // SelectionSortRoutine - sorts by finding the smallest unsorted element and moving it into place
 type SelectionSortRoutine struct {
-	data                 []int32
-	dataSize             int32
-	swapChannel          chan SwapEvent
-	comparisonChannel    chan ComparisonEvent
-	knownToBeSortedCount int32
+	sortRoutineBase
 }
 
 // NewSelectionSortRoutine factory
 func NewSelectionSortRoutine(startSlice []int32) *SelectionSortRoutine {
 	ssr := new(SelectionSortRoutine)
-	ssr.dataSize = int32(len(startSlice))
-	ssr.data = make([]int32, ssr.dataSize)
-	_ = copy(ssr.data, startSlice)
-	cc := make(chan ComparisonEvent, 1000)
-	ssr.comparisonChannel = cc
-	sc := make(chan SwapEvent, 1000)
-	ssr.swapChannel = sc
-	ssr.knownToBeSortedCount = 0
+	ssr.sortRoutineBase = newSortRoutineBase(startSlice)
 	return ssr
 }
 
-func (ssr SelectionSortRoutine) getComparisonChannel() chan ComparisonEvent {
-	return ssr.comparisonChannel
-}
-
-func (ssr SelectionSortRoutine) getSwapChannel() chan SwapEvent {
-	return ssr.swapChannel
+func init() {
+	registerSortAlgorithm(ALGORITHM_SELECTION_SORT, registeredSortAlgorithm{
+		factory:           func(startSlice []int32) SortRoutine { return NewSelectionSortRoutine(startSlice) },
+		includedByDefault: true,
+		sortedRegion:      SORTED_REGION_HEAD,
+	})
 }
 
-func (ssr SelectionSortRoutine) run() {
+func (ssr SelectionSortRoutine) run(ctx context.Context) {
 	var top int32 = int32(0)
 	var bottom int32 = int32(len(ssr.data) - 1)
 	for top = int32(0); top < bottom; top = top + 1 {
+		if ctx.Err() != nil {
+			return
+		}
 		var indexOfLowest = top
 		var scanPos int32
 		for scanPos = bottom; scanPos > top; scanPos = scanPos - 1 {
-			if compareElementsAt(ssr.data, scanPos, indexOfLowest, ssr.knownToBeSortedCount, ssr.comparisonChannel) {
+			if ssr.compareElementsAt(scanPos, indexOfLowest) {
 				indexOfLowest = scanPos
 			}
 		}
-		swapElementsAt(ssr.data, top, indexOfLowest, ssr.knownToBeSortedCount, ssr.swapChannel)
+		ssr.swapElementsAt(top, indexOfLowest)
 		ssr.knownToBeSortedCount = top
 	}
-	sortingRoutineComplete(ssr.comparisonChannel, ssr.swapChannel)
+	ssr.sortingRoutineComplete()
 }
@@ -18,49 +18,36 @@ You should have received a copy of the GNU Affero General Public License
 along with this program.  If not, see <https://www.gnu.org/licenses/>.
 */
 
-import (
-	"math"
-)
+import "context"
 
 // ShellSortRoutine - sort list by performing insertion sort on elements separated by distance N, iteratively decreasing N to 1
 type ShellSortRoutine struct {
-	data                 []int32
-	dataSize             int32
-	swapChannel          chan SwapEvent
-	comparisonChannel    chan ComparisonEvent
-	knownToBeSortedCount int32
+	sortRoutineBase
 }
 
 // NewShellSortRoutine factory
 func NewShellSortRoutine(startSlice []int32) *ShellSortRoutine {
 	ssr := new(ShellSortRoutine)
-	ssr.dataSize = int32(len(startSlice))
-	ssr.data = make([]int32, ssr.dataSize)
-	_ = copy(ssr.data, startSlice)
-	cc := make(chan ComparisonEvent, 1000)
-	ssr.comparisonChannel = cc
-	sc := make(chan SwapEvent, 1000)
-	ssr.swapChannel = sc
-	ssr.knownToBeSortedCount = 0
+	ssr.sortRoutineBase = newSortRoutineBase(startSlice)
 	return ssr
 }
 
-func (ssr ShellSortRoutine) getComparisonChannel() chan ComparisonEvent {
-	return ssr.comparisonChannel
-}
-
-func (ssr ShellSortRoutine) getSwapChannel() chan SwapEvent {
-	return ssr.swapChannel
+func init() {
+	registerSortAlgorithm(ALGORITHM_SHELL_SORT, registeredSortAlgorithm{
+		factory:           func(startSlice []int32) SortRoutine { return NewShellSortRoutine(startSlice) },
+		includedByDefault: true,
+		sortedRegion:      SORTED_REGION_ORDERED_HEAD,
+	})
 }
 
 // an insertion sort on all elements in the range separated by an interval
-func (ssr ShellSortRoutine) insertionSort(rangeToSort sortRange, interval int32) {
+func (ssr ShellSortRoutine) insertionSort(ctx context.Context, rangeToSort sortRange, interval int32) {
 	var bottom int32 = rangeToSort.top
-	for bottom <= rangeToSort.bottom-interval {
+	for bottom <= rangeToSort.bottom-interval && ctx.Err() == nil {
 		var scanPos int32
 		for scanPos = bottom + interval; scanPos > rangeToSort.top; scanPos = scanPos - interval {
-			if compareElementsAt(ssr.data, scanPos, scanPos-interval, ssr.knownToBeSortedCount, ssr.comparisonChannel) {
-				swapElementsAt(ssr.data, scanPos, scanPos-interval, ssr.knownToBeSortedCount, ssr.swapChannel)
+			if ssr.compareElementsAt(scanPos, scanPos-interval) {
+				ssr.swapElementsAt(scanPos, scanPos-interval)
 			}
 		}
 		bottom = bottom + interval
@@ -70,16 +57,17 @@ func (ssr ShellSortRoutine) insertionSort(rangeToSort sortRange, interval int32)
 	}
 }
 
-// compute a slice of intervals up to the data size (number of elemetns to be sorted)
-func (ssr ShellSortRoutine) findShellGapSizeSeries() []int32 {
+// compute a slice of intervals below the data size (number of elements to be sorted)
+// the series is empty for fewer than two elements, which are already sorted
+func findShellGapSizeSeries(dataSize int32) []int32 {
 	var shellGapSizeSeries = make([]int32, 0)
-	const shellGapSizeLimit = math.MaxInt32 / 3
-	var lower int32 = 1
-	var higher int32 = 1
-	for higher <= shellGapSizeLimit && higher < ssr.dataSize {
-		shellGapSizeSeries = append(shellGapSizeSeries, higher)
+	// the series is computed in 64 bits, as the number following the last interval may not fit in an int32
+	var lower int64 = 1
+	var higher int64 = 1
+	for higher < int64(dataSize) {
+		shellGapSizeSeries = append(shellGapSizeSeries, int32(higher))
 		// add every third fibonacci number
-		var index int32 = 0
+		var index int = 0
 		for index = 0; index < 3; index = index + 1 {
 			var total = lower + higher
 			lower = higher
@@ -90,19 +78,19 @@ func (ssr ShellSortRoutine) findShellGapSizeSeries() []int32 {
 }
 
 // iterate through interval sizes in decreasing order and call the interval insertion sort on every list partition, starting at each offset in the interval
-func (ssr ShellSortRoutine) run() {
-	var shellGapSizeSeries []int32 = ssr.findShellGapSizeSeries()
+func (ssr ShellSortRoutine) run(ctx context.Context) {
+	var shellGapSizeSeries []int32 = findShellGapSizeSeries(ssr.dataSize)
 	for intervalIndex := len(shellGapSizeSeries) - 1; intervalIndex >= 0; intervalIndex = intervalIndex - 1 {
 		var interval int32 = shellGapSizeSeries[intervalIndex]
-		var intervalRangeToBottom int32 = (ssr.dataSize / interval) * interval // probe for bottom by adding the greatest number of whole intervals forward
 		var rangeTop int32
 		for rangeTop = 0; rangeTop < interval; rangeTop = rangeTop + 1 {
-			var rangeBottom int32 = rangeTop + intervalRangeToBottom
-			if rangeBottom >= ssr.dataSize {
-				rangeBottom = rangeBottom - interval
+			// find bottom by adding the greatest number of whole intervals which stay within the data, without passing beyond it
+			var rangeBottom int32 = rangeTop + ((ssr.dataSize-1-rangeTop)/interval)*interval
+			ssr.insertionSort(ctx, sortRange{rangeTop, rangeBottom}, interval)
+			if ctx.Err() != nil {
+				return
 			}
-			ssr.insertionSort(sortRange{rangeTop, rangeBottom}, interval)
 		}
 	}
-	sortingRoutineComplete(ssr.comparisonChannel, ssr.swapChannel)
+	ssr.sortingRoutineComplete()
 }
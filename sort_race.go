@@ -0,0 +1,111 @@
+package main
+
+/*
+Parallel Sorting Demo
+Copyright (C) 2020 Robert Sheridan
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// runSortRace sorts a copy of startSlice with each algorithm concurrently and waits until every event has been processed
+// every event is passed to observer, and the returned results are in the same order as options.algorithms
+// an algorithm still sorting when ctx is done, or when its own deadline in options.deadlines passes, is cancelled,
+// and an algorithm whose routine panics fails without stopping the others
+func runSortRace(ctx context.Context, startSlice []int32, options runOptions, observer eventObserver) []*algorithmResult {
+	var algorithms []int = options.algorithms
+	var results []*algorithmResult = make([]*algorithmResult, 0, len(algorithms))
+	if len(algorithms) == 0 {
+		return results
+	}
+	var progress progressSettings = progressSettings{options.reportPeriodStep, options.progressOutput()}
+	var sortedSlice []int32 = make([]int32, len(startSlice))
+	_ = copy(sortedSlice, startSlice)
+	sort.Slice(sortedSlice, func(i, j int) bool { return sortedSlice[i] < sortedSlice[j] })
+	var lifecycle *runLifecycle = newRunLifecycle(observer)
+	// create algorithm routines and start channel processors
+	type algorithmRun struct {
+		result    *algorithmResult
+		context   context.Context
+		lifecycle *algorithmLifecycle
+		returned  context.CancelFunc // tells the channel processors that the routine will send no more events
+	}
+	var runs []algorithmRun = make([]algorithmRun, 0, len(algorithms))
+	for _, algorithm := range algorithms {
+		var algorithmContext context.Context = ctx
+		if deadline, hasDeadline := options.deadlines[algorithm]; hasDeadline {
+			var cancel context.CancelFunc
+			algorithmContext, cancel = context.WithTimeout(ctx, deadline)
+			defer cancel()
+		}
+		var sr SortRoutine = newSortRoutine(algorithm, startSlice)
+		sr.getControl().context = algorithmContext
+		sr.getControl().controller = options.controller
+		if options.scheduler != nil {
+			sr.getControl().scheduler = options.scheduler
+			options.scheduler.join()
+		}
+		var result *algorithmResult = newAlgorithmResult(algorithm, sr)
+		al, _ := lifecycle.addAlgorithm(result) // the run cannot have finished before it is waited for
+		if options.controller != nil || options.scheduler != nil {
+			// a routine waiting for the controller or the scheduler must notice when it is cancelled
+			defer context.AfterFunc(algorithmContext, func() {
+				if options.controller != nil {
+					options.controller.wake()
+				}
+				if options.scheduler != nil {
+					options.scheduler.wake()
+				}
+			})()
+		}
+		processingContext, returned := context.WithCancel(algorithmContext)
+		defer returned()
+		go processComparisonChannel(processingContext, sr.getComparisonChannel(), result, progress, observer, al.processingDone)
+		go processSwapChannel(processingContext, sr.getSwapChannel(), result, progress, observer, al.processingDone)
+		go processWriteChannel(processingContext, sr.getWriteChannel(), result, progress, observer, al.processingDone)
+		runs = append(runs, algorithmRun{result, algorithmContext, al, returned})
+		results = append(results, result)
+	}
+	// start sorting algorithms
+	fmt.Fprintln(progress.output, "beginning sorting routines")
+	for _, run := range runs {
+		go func(run algorithmRun) {
+			if options.scheduler != nil {
+				// leaving only after the finish position is decided keeps the clock from running ahead, so positions follow the ticks
+				defer options.scheduler.leave()
+			}
+			defer run.returned()
+			defer run.lifecycle.routineReturned()
+			var result *algorithmResult = run.result
+			var start time.Time = time.Now()
+			result.panicked = runGuarded(run.context, result.routine)
+			result.elapsed = time.Since(start)
+			result.ticks = result.routine.getControl().dueAtTick
+			// a routine which panicked has failed, even if the data happens to be in order
+			result.sortedCorrectly = result.panicked == nil && firstOutOfOrderPosition(result.routine.getData()) < 0
+			result.sortedFraction = sortedFraction(result.routine.getData(), sortedSlice)
+			// a routine which sorted the data just as it was cancelled is not counted as cancelled
+			result.cancelled = run.context.Err() != nil && !result.sortedCorrectly && result.panicked == nil
+		}(run)
+	}
+	lifecycle.wait()
+	rankResults(results)
+	return results
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRaceCancelsAlgorithmsPastTheirDeadlines(t *testing.T) {
+	var options runOptions = raceOptionsForTest([]int{ALGORITHM_BUBBLE_SORT, ALGORITHM_QUICK_SORT}, 20000, 17)
+	options.deadlines = map[int]time.Duration{ALGORITHM_BUBBLE_SORT: 20 * time.Millisecond}
+	var results []*algorithmResult = runSortRace(context.Background(), makeDataArray(options.data), options, eventObservers{})
+	if !results[0].cancelled || results[0].rank != 0 || results[0].sortedFraction >= 1 {
+		t.Errorf("bubble sort was not cancelled: %+v", *results[0])
+	}
+	if results[1].cancelled || !results[1].sortedCorrectly || results[1].rank != 1 {
+		t.Errorf("quick sort was affected by the deadline of bubble sort: %+v", *results[1])
+	}
+}
+
+func TestCancellingReleasesPausedAndLockstepRoutines(t *testing.T) {
+	var options runOptions = raceOptionsForTest(defaultAlgorithms(), 500, 19)
+	options.controller = newRunController()
+	options.controller.pause()
+	options.scheduler = newLockstepScheduler(1, 1, 1)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	var finished chan []*algorithmResult = make(chan []*algorithmResult)
+	go func() {
+		finished <- runSortRace(ctx, makeDataArray(options.data), options, eventObservers{})
+	}()
+	options.controller.step(10)
+	select {
+	case results := <-finished:
+		for _, result := range results {
+			if !result.cancelled {
+				t.Errorf("%s was not cancelled", algorithmName[result.algorithm])
+			}
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("race did not finish after being cancelled")
+	}
+}
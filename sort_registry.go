@@ -0,0 +1,92 @@
+package main
+
+/*
+Parallel Sorting Demo
+Copyright (C) 2020 Robert Sheridan
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// sortRoutineFactory creates a routine which sorts its own copy of startSlice
+type sortRoutineFactory func(startSlice []int32) SortRoutine
+
+type registeredSortAlgorithm struct {
+	factory           sortRoutineFactory
+	includedByDefault bool // whether the algorithm races when no algorithms are chosen explicitly
+	sortedRegion      int  // where the elements counted by knownToBeSortedCount are (one of the SORTED_REGION_ values)
+}
+
+var sortAlgorithmRegistry = map[int]registeredSortAlgorithm{}
+
+// registerSortAlgorithm makes an algorithm available for racing - each routine registers itself from init()
+func registerSortAlgorithm(algorithm int, registration registeredSortAlgorithm) {
+	if algorithm <= 0 || algorithm >= len(algorithmName) {
+		panic(fmt.Sprintf("cannot register unknown algorithm %d", algorithm))
+	}
+	if _, exists := sortAlgorithmRegistry[algorithm]; exists {
+		panic("algorithm " + algorithmName[algorithm] + " registered twice")
+	}
+	sortAlgorithmRegistry[algorithm] = registration
+}
+
+// registeredAlgorithms lists every registered algorithm in order of algorithm id
+func registeredAlgorithms() []int {
+	var algorithms []int = make([]int, 0, len(sortAlgorithmRegistry))
+	for algorithm := range sortAlgorithmRegistry {
+		algorithms = append(algorithms, algorithm)
+	}
+	sort.Ints(algorithms)
+	return algorithms
+}
+
+// defaultAlgorithms lists the registered algorithms which race when no algorithms are chosen explicitly
+func defaultAlgorithms() []int {
+	var algorithms []int = make([]int, 0, len(sortAlgorithmRegistry))
+	for _, algorithm := range registeredAlgorithms() {
+		if sortAlgorithmRegistry[algorithm].includedByDefault {
+			algorithms = append(algorithms, algorithm)
+		}
+	}
+	return algorithms
+}
+
+// shortAlgorithmName is the algorithm name without the trailing " sort", as used on the command line
+func shortAlgorithmName(algorithm int) string {
+	return strings.TrimSuffix(algorithmName[algorithm], " sort")
+}
+
+// findRegisteredAlgorithm looks up a registered algorithm by its name, with or without the trailing " sort"
+func findRegisteredAlgorithm(name string) (int, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, algorithm := range registeredAlgorithms() {
+		if name == algorithmName[algorithm] || name == shortAlgorithmName(algorithm) {
+			return algorithm, true
+		}
+	}
+	return 0, false
+}
+
+func newSortRoutine(algorithm int, startSlice []int32) SortRoutine {
+	registered, exists := sortAlgorithmRegistry[algorithm]
+	if !exists {
+		panic(fmt.Sprintf("algorithm %d is not registered", algorithm))
+	}
+	return registered.factory(startSlice)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestEveryAlgorithmSortsEdgeCaseSizes(t *testing.T) {
+	var inputs [][]int32 = [][]int32{
+		{},
+		{7},
+		{1, 2},
+		{2, 1},
+		{2, 2},
+		{1, 2, 3},
+		{3, 2, 1},
+		{2, 3, 1},
+		{1, 1, 0},
+		{7, 6, 5, 4, 3, 2, 1},    // the smallest range quick sort partitions
+		{5, 1, 5, 1, 5, 1, 5, 1}, // duplicates either side of a pivot
+	}
+	for _, input := range inputs {
+		t.Run(fmt.Sprintf("size %d %v", len(input), input), func(t *testing.T) {
+			// the race is given input rather than data generated from its options
+			var options runOptions = raceOptionsForTest(registeredAlgorithms(), int32(len(input)), 0)
+			var collector *traceEventCollector = new(traceEventCollector)
+			var results []*algorithmResult = runSortRace(context.Background(), input, options, collector)
+			for _, result := range results {
+				// random sort may give up once there are too many orderings to shuffle through, but nothing may fail
+				if err := result.failure(); err != nil && !result.gaveUp {
+					t.Errorf("%s: %v with data %v", algorithmName[result.algorithm], err, result.routine.getData())
+				}
+				var replay *traceReplay = newTraceReplay(input, collector.collectedEvents(), result.algorithm)
+				final, err := replay.seek(replay.eventCount())
+				if err != nil {
+					t.Errorf("%s: %v", algorithmName[result.algorithm], err)
+					continue
+				}
+				if fmt.Sprint(final) != fmt.Sprint(result.routine.getData()) {
+					t.Errorf("%s events replay to %v rather than %v", algorithmName[result.algorithm], final, result.routine.getData())
+				}
+			}
+		})
+	}
+}
+
+func TestShellGapSizeSeriesStaysWithinTheDataSize(t *testing.T) {
+	var tests = []struct {
+		dataSize int32
+		expected []int32
+	}{
+		{0, []int32{}},
+		{1, []int32{}},
+		{2, []int32{1}},
+		{5, []int32{1}},
+		{6, []int32{1, 5}},
+	}
+	for _, test := range tests {
+		if series := findShellGapSizeSeries(test.dataSize); fmt.Sprint(series) != fmt.Sprint(test.expected) {
+			t.Errorf("gap series for %d elements is %v, expected %v", test.dataSize, series, test.expected)
+		}
+	}
+	var series []int32 = findShellGapSizeSeries(math.MaxInt32)
+	for pos := 1; pos < len(series); pos = pos + 1 {
+		if series[pos] <= series[pos-1] {
+			t.Fatalf("gap series for the largest data size is not increasing: %v", series)
+		}
+	}
+	if series[len(series)-1] < math.MaxInt32/5 {
+		t.Errorf("gap series for the largest data size stops early: %v", series)
+	}
+}
+
+func TestLastPositionInRangeDoesNotOverflow(t *testing.T) {
+	var tests = []struct {
+		start    int32
+		length   int32
+		dataSize int32
+		expected int32
+	}{
+		{0, 1, 1, 0},
+		{4, 2, 10, 5},
+		{8, 4, 10, 9},
+		{math.MaxInt32 - 10, 1 << 30, math.MaxInt32, math.MaxInt32 - 1},
+		{1 << 30, 1 << 30, math.MaxInt32, math.MaxInt32 - 1},
+		{1<<30 + 1, 1 << 30, math.MaxInt32, math.MaxInt32 - 1},
+	}
+	for _, test := range tests {
+		if last := lastPositionInRange(test.start, test.length, test.dataSize); last != test.expected {
+			t.Errorf("last position of %d elements from %d within %d is %d, expected %d", test.length, test.start, test.dataSize, last, test.expected)
+		}
+	}
+}
+
+func TestLeftChildInHeapDoesNotOverflow(t *testing.T) {
+	var tests = []struct {
+		root     int32
+		end      int32
+		expected int32
+		found    bool
+	}{
+		{0, 1, 0, false},
+		{0, 2, 1, true},
+		{1, 3, 0, false},
+		{1, 4, 3, true},
+		{math.MaxInt32/2 - 1, math.MaxInt32, math.MaxInt32 - 2, true},
+		{math.MaxInt32 / 2, math.MaxInt32, 0, false},
+		{1 << 30, math.MaxInt32, 0, false},
+		{math.MaxInt32 - 1, math.MaxInt32, 0, false},
+	}
+	for _, test := range tests {
+		if child, found := leftChildInHeap(test.root, test.end); child != test.expected || found != test.found {
+			t.Errorf("left child of %d in a heap of %d elements is %d (found %v), expected %d (found %v)", test.root, test.end, child, found, test.expected, test.found)
+		}
+	}
+}
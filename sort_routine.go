@@ -0,0 +1,87 @@
+package main
+
+/*
+Parallel Sorting Demo
+Copyright (C) 2020 Robert Sheridan
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import "context"
+
+// SortRoutine is implemented by every sorting algorithm which can take part in a race
+type SortRoutine interface {
+	getComparisonChannel() chan ComparisonEvent
+	getSwapChannel() chan SwapEvent
+	getWriteChannel() chan WriteEvent
+	getData() []int32
+	getDataSize() int32
+	getControl() *routineControl
+	run(ctx context.Context)
+}
+
+// sortRoutineBase holds the data and event channels common to every sorting routine
+type sortRoutineBase struct {
+	data                 []int32
+	dataSize             int32
+	swapChannel          chan SwapEvent
+	comparisonChannel    chan ComparisonEvent
+	writeChannel         chan WriteEvent
+	knownToBeSortedCount int32
+	eventSequence        *int64          // the sequence number of the latest event, shared by every copy of the routine
+	control              *routineControl // how the routine is paused or paced, shared by every copy of the routine
+}
+
+func newSortRoutineBase(startSlice []int32) sortRoutineBase {
+	var b sortRoutineBase
+	b.dataSize = int32(len(startSlice))
+	b.data = make([]int32, b.dataSize)
+	_ = copy(b.data, startSlice)
+	cc := make(chan ComparisonEvent, 1000)
+	b.comparisonChannel = cc
+	sc := make(chan SwapEvent, 1000)
+	b.swapChannel = sc
+	wc := make(chan WriteEvent, 1000)
+	b.writeChannel = wc
+	b.knownToBeSortedCount = 0
+	b.eventSequence = new(int64)
+	b.control = new(routineControl)
+	b.control.context = context.Background()
+	return b
+}
+
+func (b sortRoutineBase) getComparisonChannel() chan ComparisonEvent {
+	return b.comparisonChannel
+}
+
+func (b sortRoutineBase) getSwapChannel() chan SwapEvent {
+	return b.swapChannel
+}
+
+func (b sortRoutineBase) getWriteChannel() chan WriteEvent {
+	return b.writeChannel
+}
+
+func (b sortRoutineBase) getData() []int32 {
+	return b.data
+}
+
+func (b sortRoutineBase) getDataSize() int32 {
+	return b.dataSize
+}
+
+// getControl returns how the routine is held back, to be set up before it runs
+func (b sortRoutineBase) getControl() *routineControl {
+	return b.control
+}
@@ -18,26 +18,107 @@ You should have received a copy of the GNU Affero General Public License
 along with this program.  If not, see <https://www.gnu.org/licenses/>.
 */
 
+import "time"
+
 type sortRange struct {
 	top    int32
 	bottom int32
 }
 
-func sortingRoutineComplete(cc chan ComparisonEvent, sc chan SwapEvent) {
-	cc <- sortingCompleteComparisonEvent
-	sc <- sortingCompleteSwapEvent
+// lastPositionInRange finds the last position of the range of length elements starting at start, cut short at the end of the data
+// (start + length may not fit in an int32 when the data is close to the largest possible size)
+func lastPositionInRange(start int32, length int32, dataSize int32) int32 {
+	if length > dataSize-start {
+		return dataSize - 1
+	}
+	return start + length - 1
+}
+
+// signal that the routine has sorted the data and will send no more events
+func (b sortRoutineBase) sortingRoutineComplete() {
+	b.sendComparison(sortingCompleteComparisonEvent)
+	b.sendSwap(sortingCompleteSwapEvent)
+	b.sendWrite(sortingCompleteWriteEvent)
+}
+
+// signal that the routine stopped before the data was sorted
+func (b sortRoutineBase) sortingRoutineAbandoned() {
+	b.sendComparison(sortingAbandonedComparisonEvent)
+	b.sendSwap(sortingAbandonedSwapEvent)
+	b.sendWrite(sortingAbandonedWriteEvent)
+}
+
+// the send functions drop the event once the routine is cancelled, as its channel processor may no longer be receiving
+
+func (b sortRoutineBase) sendComparison(e ComparisonEvent) {
+	select {
+	case b.comparisonChannel <- e:
+	case <-b.control.context.Done():
+	}
+}
+
+func (b sortRoutineBase) sendSwap(e SwapEvent) {
+	select {
+	case b.swapChannel <- e:
+	case <-b.control.context.Done():
+	}
 }
 
-func compareElementsAt(data []int32, i int32, j int32, ktbsc int32, c chan ComparisonEvent) bool {
-	var e ComparisonEvent = ComparisonEvent{[2]int32{i, j}, [2]int32{data[i], data[j]}, data[i] < data[j], ktbsc}
-	c <- e
+func (b sortRoutineBase) sendWrite(e WriteEvent) {
+	select {
+	case b.writeChannel <- e:
+	case <-b.control.context.Done():
+	}
+}
+
+func (b sortRoutineBase) nextEventSequence() int64 {
+	*b.eventSequence = *b.eventSequence + 1
+	return *b.eventSequence
+}
+
+// awaitPermission blocks while the routine's controller holds it back, and then until the lockstep clock grants the event of eventKind (a TRACE_EVENT_ value)
+func (b sortRoutineBase) awaitPermission(eventKind byte) {
+	if b.control.context.Err() != nil {
+		return // a cancelled routine is only finishing its current step, so there is nothing to wait for
+	}
+	if b.control.controller != nil {
+		b.control.controller.await(b.control)
+	}
+	if b.control.scheduler != nil {
+		b.control.scheduler.await(b.control, b.control.scheduler.costOf(eventKind))
+	}
+}
+
+func (b sortRoutineBase) compareElementsAt(i int32, j int32) bool {
+	b.awaitPermission(TRACE_EVENT_COMPARISON)
+	var e ComparisonEvent = ComparisonEvent{[2]int32{i, j}, [2]int32{b.data[i], b.data[j]}, b.data[i] < b.data[j], b.knownToBeSortedCount, b.nextEventSequence(), time.Now().UnixNano()}
+	b.control.recentEvents.add(traceEvent{kind: TRACE_EVENT_COMPARISON, comparison: e})
+	b.sendComparison(e)
 	return e.firstWasLower
 }
 
-func swapElementsAt(data []int32, i int32, j int32, ktbsc int32, c chan SwapEvent) {
-	var e SwapEvent = SwapEvent{[2]int32{i, j}, [2]int32{data[i], data[j]}, ktbsc}
-	c <- e
-	var t int32 = data[i]
-	data[i] = data[j]
-	data[j] = t
+func (b sortRoutineBase) swapElementsAt(i int32, j int32) {
+	b.awaitPermission(TRACE_EVENT_SWAP)
+	var e SwapEvent = SwapEvent{[2]int32{i, j}, [2]int32{b.data[i], b.data[j]}, b.knownToBeSortedCount, b.nextEventSequence(), time.Now().UnixNano()}
+	b.control.recentEvents.add(traceEvent{kind: TRACE_EVENT_SWAP, swap: e})
+	b.sendSwap(e)
+	var t int32 = b.data[i]
+	b.data[i] = b.data[j]
+	b.data[j] = t
+}
+
+func (b sortRoutineBase) copyElementToAuxiliary(auxiliary []int32, auxiliaryIndex int32, dataIndex int32) {
+	b.awaitPermission(TRACE_EVENT_WRITE)
+	var e WriteEvent = WriteEvent{[2]int32{auxiliaryIndex, dataIndex}, [2]int32{auxiliary[auxiliaryIndex], b.data[dataIndex]}, true, b.knownToBeSortedCount, b.nextEventSequence(), time.Now().UnixNano()}
+	b.control.recentEvents.add(traceEvent{kind: TRACE_EVENT_WRITE, write: e})
+	b.sendWrite(e)
+	auxiliary[auxiliaryIndex] = b.data[dataIndex]
+}
+
+func (b sortRoutineBase) copyElementFromAuxiliary(auxiliary []int32, dataIndex int32, auxiliaryIndex int32) {
+	b.awaitPermission(TRACE_EVENT_WRITE)
+	var e WriteEvent = WriteEvent{[2]int32{dataIndex, auxiliaryIndex}, [2]int32{b.data[dataIndex], auxiliary[auxiliaryIndex]}, false, b.knownToBeSortedCount, b.nextEventSequence(), time.Now().UnixNano()}
+	b.control.recentEvents.add(traceEvent{kind: TRACE_EVENT_WRITE, write: e})
+	b.sendWrite(e)
+	b.data[dataIndex] = auxiliary[auxiliaryIndex]
 }
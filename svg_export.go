@@ -0,0 +1,165 @@
+package main
+
+/*
+Parallel Sorting Demo
+Copyright (C) 2020 Robert Sheridan
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+const SVG_PANEL_WIDTH float64 = 800
+const SVG_PANEL_HEIGHT float64 = 200
+const SVG_LABEL_HEIGHT float64 = 24
+const SVG_MARGIN float64 = 8
+const SVG_FRAME_DURATION time.Duration = 50 * time.Millisecond
+
+const SVG_BAR_COLOR string = "#4a90d9"
+const SVG_COMPARED_COLOR string = "#f5a623"
+
+// svgKeyframe is a change in how one bar is drawn, starting at frame
+type svgKeyframe struct {
+	frame int
+	value string
+}
+
+// svgBar collects the changes of height and colour of the bar at one position, so that unchanging frames cost nothing
+type svgBar struct {
+	heights []svgKeyframe
+	colors  []svgKeyframe
+}
+
+func appendKeyframe(keyframes []svgKeyframe, frame int, value string) []svgKeyframe {
+	if len(keyframes) > 0 && keyframes[len(keyframes)-1].value == value {
+		return keyframes
+	}
+	return append(keyframes, svgKeyframe{frame, value})
+}
+
+// barHeightScale turns element values into bar heights, leaving the lowest value a sliver so that every element is visible
+type barHeightScale struct {
+	lowestValue int32
+	valueRange  int64
+}
+
+func newBarHeightScale(startSlice []int32) barHeightScale {
+	var lowest, highest int32
+	for pos, value := range startSlice {
+		if pos == 0 || value < lowest {
+			lowest = value
+		}
+		if pos == 0 || value > highest {
+			highest = value
+		}
+	}
+	return barHeightScale{lowest, int64(highest) - int64(lowest)}
+}
+
+// barHeight is the height of the bar for value when the highest value fills fullHeight
+func (scale barHeightScale) barHeight(value int32, fullHeight float64) float64 {
+	if scale.valueRange == 0 {
+		return fullHeight
+	}
+	return 1 + float64(int64(value)-int64(scale.lowestValue))*(fullHeight-1)/float64(scale.valueRange)
+}
+
+// writeSVGAnimation writes one element of a bar whose values change at the given frames, out of frameCount frames
+func writeSVGAnimation(w io.Writer, attribute string, keyframes []svgKeyframe, frameCount int) {
+	var values, keyTimes []string
+	for _, keyframe := range keyframes {
+		values = append(values, keyframe.value)
+		keyTimes = append(keyTimes, fmt.Sprintf("%.4f", float64(keyframe.frame)/float64(frameCount)))
+	}
+	fmt.Fprintf(w, `<animate attributeName="%s" values="%s" keyTimes="%s" calcMode="discrete" dur="%.2fs" fill="freeze"/>`,
+		attribute, strings.Join(values, ";"), strings.Join(keyTimes, ";"), (time.Duration(frameCount) * SVG_FRAME_DURATION).Seconds())
+}
+
+// writeAnimatedSVG replays the events of each algorithm from startSlice and writes a self-contained SVG which animates the bars with SMIL,
+// one panel per algorithm, highlighting the latest compared pair of each frame
+// no more than frameBudget frames are drawn, so with large inputs each frame coalesces many events
+func writeAnimatedSVG(w io.Writer, startSlice []int32, events []traceEvent, algorithms []int, frameBudget int) error {
+	var eventsPerFrame int = eventsPerFrameWithin(events, algorithms, frameBudget)
+	var scale barHeightScale = newBarHeightScale(startSlice)
+	var panels [][]svgBar = make([][]svgBar, len(algorithms))
+	var frameCount int = 1
+	for p, algorithm := range algorithms {
+		var bars []svgBar = make([]svgBar, len(startSlice))
+		var replay *traceReplay = newTraceReplay(startSlice, events, algorithm)
+		err := replay.replayFrames(eventsPerFrame, func(frame int, data []int32, compared [2]int32) {
+			for pos, value := range data {
+				var color string = SVG_BAR_COLOR
+				if int32(pos) == compared[0] || int32(pos) == compared[1] {
+					color = SVG_COMPARED_COLOR
+				}
+				bars[pos].heights = appendKeyframe(bars[pos].heights, frame, fmt.Sprintf("%.1f", scale.barHeight(value, SVG_PANEL_HEIGHT)))
+				bars[pos].colors = appendKeyframe(bars[pos].colors, frame, color)
+			}
+			if frame+1 > frameCount {
+				frameCount = frame + 1
+			}
+		})
+		if err != nil {
+			return err
+		}
+		panels[p] = bars
+	}
+	frameCount = frameCount + 1 // hold the final frame before the animation freezes
+	var panelStep float64 = SVG_LABEL_HEIGHT + SVG_PANEL_HEIGHT + SVG_MARGIN
+	var barWidth float64 = SVG_PANEL_WIDTH / float64(len(startSlice))
+	var bw *bufio.Writer = bufio.NewWriter(w)
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%.0f" height="%.0f" viewBox="0 0 %.0f %.0f">`+"\n",
+		SVG_PANEL_WIDTH+2*SVG_MARGIN, float64(len(algorithms))*panelStep+SVG_MARGIN, SVG_PANEL_WIDTH+2*SVG_MARGIN, float64(len(algorithms))*panelStep+SVG_MARGIN)
+	fmt.Fprintln(bw, `<rect width="100%" height="100%" fill="white"/>`)
+	for p, algorithm := range algorithms {
+		var top float64 = SVG_MARGIN + float64(p)*panelStep
+		fmt.Fprintf(bw, `<text x="%.0f" y="%.0f" font-family="sans-serif" font-size="16">%s (%d events)</text>`+"\n",
+			SVG_MARGIN, top+SVG_LABEL_HEIGHT-6, algorithmName[algorithm], len(eventsOfAlgorithm(events, algorithm)))
+		// bars grow upwards from the bottom of the panel, so only their heights need animating
+		fmt.Fprintf(bw, `<g transform="translate(%.0f %.0f) scale(1 -1)">`+"\n", SVG_MARGIN, top+SVG_LABEL_HEIGHT+SVG_PANEL_HEIGHT)
+		for pos, bar := range panels[p] {
+			fmt.Fprintf(bw, `<rect x="%.3f" width="%.3f" height="%s" fill="%s">`, float64(pos)*barWidth, barWidth, bar.heights[0].value, bar.colors[0].value)
+			if len(bar.heights) > 1 {
+				writeSVGAnimation(bw, "height", bar.heights, frameCount)
+			}
+			if len(bar.colors) > 1 {
+				writeSVGAnimation(bw, "fill", bar.colors, frameCount)
+			}
+			fmt.Fprintln(bw, "</rect>")
+		}
+		fmt.Fprintln(bw, "</g>")
+	}
+	fmt.Fprintln(bw, "</svg>")
+	return bw.Flush()
+}
+
+// exportAnimatedSVG writes the animation of a race to the file at path
+func exportAnimatedSVG(path string, startSlice []int32, events []traceEvent, algorithms []int, frameBudget int) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := writeAnimatedSVG(file, startSlice, events, algorithms, frameBudget); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
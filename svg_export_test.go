@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestAnimatedSVGStaysWithinFrameBudget(t *testing.T) {
+	var algorithms []int = []int{ALGORITHM_QUICK_SORT, ALGORITHM_MERGE_SORT}
+	var collector *traceEventCollector = new(traceEventCollector)
+	startSlice, _ := raceForTest(t, algorithms, 200, 3, collector)
+	var svg bytes.Buffer
+	if err := writeAnimatedSVG(&svg, startSlice, collector.collectedEvents(), algorithms, 20); err != nil {
+		t.Fatal(err)
+	}
+	var decoder *xml.Decoder = xml.NewDecoder(bytes.NewReader(svg.Bytes()))
+	var animations int = 0
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			if err != io.EOF {
+				t.Fatal(err)
+			}
+			break
+		}
+		element, isStart := token.(xml.StartElement)
+		if !isStart || element.Name.Local != "animate" {
+			continue
+		}
+		animations = animations + 1
+		for _, attribute := range element.Attr {
+			if attribute.Name.Local == "keyTimes" && len(strings.Split(attribute.Value, ";")) > 21 {
+				t.Fatalf("animation has %d keyframes, more than the budget of 20 frames allows", len(strings.Split(attribute.Value, ";")))
+			}
+		}
+	}
+	if animations == 0 {
+		t.Fatal("no bar was animated")
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+/*
+Parallel Sorting Demo
+Copyright (C) 2020 Robert Sheridan
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const DASHBOARD_FRAME_INTERVAL time.Duration = 100 * time.Millisecond
+const DASHBOARD_BAR_WIDTH int = 30
+
+const ansiClearScreen string = "\x1b[2J"
+const ansiCursorHome string = "\x1b[H"
+const ansiClearToEndOfLine string = "\x1b[K"
+const ansiHideCursor string = "\x1b[?25l"
+const ansiShowCursor string = "\x1b[?25h"
+
+// isTerminal tells whether f is an interactive terminal rather than a file or pipe
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// frameLoop calls draw at a steady interval on its own goroutine, and once more when it is ended
+type frameLoop struct {
+	stop    chan bool
+	stopped chan bool
+}
+
+func startFrameLoop(interval time.Duration, draw func()) *frameLoop {
+	var loop *frameLoop = &frameLoop{make(chan bool), make(chan bool)}
+	go func() {
+		var ticker *time.Ticker = time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				draw()
+			case <-loop.stop:
+				draw()
+				loop.stopped <- true
+				return
+			}
+		}
+	}()
+	return loop
+}
+
+// end stops the loop after drawing the final frame
+func (loop *frameLoop) end() {
+	loop.stop <- true
+	<-loop.stopped
+}
+
+// dashboardRow holds what the dashboard shows for one algorithm
+type dashboardRow struct {
+	algorithm            int
+	knownToBeSortedCount int32
+	comparisons          int64
+	swaps                int64
+	writes               int64
+	elapsed              time.Duration
+	finishPosition       int   // 0 while still sorting
+	err                  error // why the algorithm did not sort the data, once it has finished
+}
+
+// terminalDashboard is an eventObserver which redraws one row per racing algorithm in place
+type terminalDashboard struct {
+	mutex    sync.Mutex
+	output   io.Writer
+	dataSize int32
+	rows     []*dashboardRow
+	rowOf    map[int]*dashboardRow
+	start    time.Time
+	frames   *frameLoop
+}
+
+func newTerminalDashboard(output io.Writer, algorithms []int, dataSize int32) *terminalDashboard {
+	dashboard := new(terminalDashboard)
+	dashboard.output = output
+	dashboard.dataSize = dataSize
+	dashboard.rowOf = map[int]*dashboardRow{}
+	for _, algorithm := range algorithms {
+		var row *dashboardRow = &dashboardRow{algorithm: algorithm}
+		dashboard.rows = append(dashboard.rows, row)
+		dashboard.rowOf[algorithm] = row
+	}
+	return dashboard
+}
+
+// begin clears the screen and starts redrawing every DASHBOARD_FRAME_INTERVAL until end is called
+func (dashboard *terminalDashboard) begin() {
+	dashboard.start = time.Now()
+	fmt.Fprint(dashboard.output, ansiHideCursor+ansiClearScreen)
+	dashboard.frames = startFrameLoop(DASHBOARD_FRAME_INTERVAL, dashboard.draw)
+}
+
+// end draws the final state and gives the terminal back
+func (dashboard *terminalDashboard) end() {
+	dashboard.frames.end()
+	fmt.Fprint(dashboard.output, ansiShowCursor)
+}
+
+func (dashboard *terminalDashboard) update(algorithm int, knownToBeSortedCount int32, comparisons int64, swaps int64, writes int64) {
+	dashboard.mutex.Lock()
+	defer dashboard.mutex.Unlock()
+	var row *dashboardRow = dashboard.rowOf[algorithm]
+	if row == nil {
+		return
+	}
+	if knownToBeSortedCount > row.knownToBeSortedCount {
+		row.knownToBeSortedCount = knownToBeSortedCount
+	}
+	row.comparisons = row.comparisons + comparisons
+	row.swaps = row.swaps + swaps
+	row.writes = row.writes + writes
+}
+
+func (dashboard *terminalDashboard) observeComparison(algorithm int, ce ComparisonEvent) {
+	dashboard.update(algorithm, ce.knownToBeSortedCount, 1, 0, 0)
+}
+
+func (dashboard *terminalDashboard) observeSwap(algorithm int, se SwapEvent) {
+	dashboard.update(algorithm, se.knownToBeSortedCount, 0, 1, 0)
+}
+
+func (dashboard *terminalDashboard) observeWrite(algorithm int, we WriteEvent) {
+	dashboard.update(algorithm, we.knownToBeSortedCount, 0, 0, 1)
+}
+
+func (dashboard *terminalDashboard) observeLifecycle(event lifecycleEvent) {
+	if event.kind != LIFECYCLE_ALGORITHM_FINISHED {
+		return
+	}
+	dashboard.mutex.Lock()
+	defer dashboard.mutex.Unlock()
+	var row *dashboardRow = dashboard.rowOf[event.algorithm]
+	if row == nil {
+		return
+	}
+	row.finishPosition = event.finishPosition
+	row.elapsed = event.result.elapsed
+	row.err = event.err
+}
+
+func progressBar(proportion float32, width int) string {
+	var filled int = int(proportion * float32(width))
+	if filled > width {
+		filled = width
+	}
+	return "[" + strings.Repeat("#", filled) + strings.Repeat("-", width-filled) + "]"
+}
+
+func (dashboard *terminalDashboard) formatRow(row *dashboardRow) string {
+	var proportion float32 = proportionSorted(row.knownToBeSortedCount, dashboard.dataSize)
+	var elapsed time.Duration = row.elapsed
+	var finish string = "sorting"
+	if row.finishPosition > 0 && row.err != nil {
+		finish = fmt.Sprintf("finished #%d - %s", row.finishPosition, row.err)
+	} else if row.finishPosition > 0 {
+		proportion = 1.0
+		finish = fmt.Sprintf("finished #%d", row.finishPosition)
+	} else {
+		elapsed = time.Since(dashboard.start)
+	}
+	return fmt.Sprintf("%-15s %s %3.0f%%  %10d comparisons %10d swaps %10d writes %9s  %s",
+		algorithmName[row.algorithm], progressBar(proportion, DASHBOARD_BAR_WIDTH), proportion*100,
+		row.comparisons, row.swaps, row.writes, elapsed.Round(time.Millisecond), finish)
+}
+
+func (dashboard *terminalDashboard) draw() {
+	dashboard.mutex.Lock()
+	var frame strings.Builder
+	frame.WriteString(ansiCursorHome)
+	fmt.Fprintf(&frame, "racing %d algorithms on %d elements%s\n", len(dashboard.rows), dashboard.dataSize, ansiClearToEndOfLine)
+	for _, row := range dashboard.rows {
+		frame.WriteString(dashboard.formatRow(row) + ansiClearToEndOfLine + "\n")
+	}
+	dashboard.mutex.Unlock()
+	fmt.Fprint(dashboard.output, frame.String())
+}
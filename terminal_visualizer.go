@@ -0,0 +1,261 @@
+package main
+
+/*
+Parallel Sorting Demo
+Copyright (C) 2020 Robert Sheridan
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const VISUALIZATION_DEFAULT_WIDTH int = 80
+const VISUALIZATION_DEFAULT_HEIGHT int = 24
+const VISUALIZATION_PANEL_GAP int = 1
+
+// the block characters used to draw the top of a bar, in eighths of a terminal row
+var barBlocks = []rune{' ', '▁', '▂', '▃', '▄', '▅', '▆', '▇', '█'}
+
+const ansiResetColor string = "\x1b[0m"
+const ansiBarColor string = "\x1b[36m"
+const ansiComparedColor string = "\x1b[33m"
+const ansiSwappedColor string = "\x1b[31m"
+const ansiWrittenColor string = "\x1b[32m"
+
+// highlights of a column since the previous frame, most prominent last
+const (
+	HIGHLIGHT_NONE byte = iota
+	HIGHLIGHT_COMPARED
+	HIGHLIGHT_WRITTEN
+	HIGHLIGHT_SWAPPED
+)
+
+var highlightColor = []string{ansiBarColor, ansiComparedColor, ansiWrittenColor, ansiSwappedColor}
+
+// terminalSize asks the terminal on standard output for its size, falling back to the COLUMNS and LINES environment
+// variables when standard output is not a terminal, and then to 80x24
+func terminalSize() (int, int) {
+	if width, height, found := terminalWindowSize(os.Stdout); found {
+		return width, height
+	}
+	var width int = VISUALIZATION_DEFAULT_WIDTH
+	var height int = VISUALIZATION_DEFAULT_HEIGHT
+	if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 0 {
+		width = columns
+	}
+	if lines, err := strconv.Atoi(os.Getenv("LINES")); err == nil && lines > 0 {
+		height = lines
+	}
+	return width, height
+}
+
+// visualizerPanel holds one algorithm's copy of the data as rebuilt from its swap and write events
+type visualizerPanel struct {
+	algorithm  int
+	data       []int32
+	highlights []byte // per column of the panel
+}
+
+// terminalVisualizer is an eventObserver which draws the data of every racing algorithm as vertical bars, side by side
+// when there are more elements than columns, each column shows the average of the elements it covers
+type terminalVisualizer struct {
+	mutex       sync.Mutex
+	output      io.Writer
+	frameRate   int
+	panels      []*visualizerPanel
+	panelOf     map[int]*visualizerPanel
+	panelWidth  int
+	barHeight   int
+	lowestValue int32
+	valueRange  int64
+	frames      *frameLoop
+}
+
+func newTerminalVisualizer(output io.Writer, algorithms []int, startSlice []int32, frameRate int, width int, height int) *terminalVisualizer {
+	visualizer := new(terminalVisualizer)
+	visualizer.output = output
+	visualizer.frameRate = frameRate
+	visualizer.panelOf = map[int]*visualizerPanel{}
+	visualizer.panelWidth = (width - VISUALIZATION_PANEL_GAP*(len(algorithms)-1)) / len(algorithms)
+	if visualizer.panelWidth < 1 {
+		visualizer.panelWidth = 1
+	}
+	if visualizer.panelWidth > len(startSlice) && len(startSlice) > 0 {
+		visualizer.panelWidth = len(startSlice)
+	}
+	visualizer.barHeight = height - 2 // leave room for the names and the cursor
+	if visualizer.barHeight < 1 {
+		visualizer.barHeight = 1
+	}
+	var lowest, highest int32
+	for pos, value := range startSlice {
+		if pos == 0 || value < lowest {
+			lowest = value
+		}
+		if pos == 0 || value > highest {
+			highest = value
+		}
+	}
+	visualizer.lowestValue = lowest
+	visualizer.valueRange = int64(highest) - int64(lowest)
+	for _, algorithm := range algorithms {
+		var panel *visualizerPanel = &visualizerPanel{algorithm, make([]int32, len(startSlice)), make([]byte, visualizer.panelWidth)}
+		_ = copy(panel.data, startSlice)
+		visualizer.panels = append(visualizer.panels, panel)
+		visualizer.panelOf[algorithm] = panel
+	}
+	return visualizer
+}
+
+// begin clears the screen and starts drawing frameRate frames per second until end is called
+func (visualizer *terminalVisualizer) begin() {
+	fmt.Fprint(visualizer.output, ansiHideCursor+ansiClearScreen)
+	visualizer.frames = startFrameLoop(time.Second/time.Duration(visualizer.frameRate), visualizer.draw)
+}
+
+// end draws the final state and gives the terminal back
+func (visualizer *terminalVisualizer) end() {
+	visualizer.frames.end()
+	fmt.Fprint(visualizer.output, ansiResetColor+ansiShowCursor)
+}
+
+// columnOf finds the panel column which shows the element at index - the one whose range in columnHeight contains it
+func (visualizer *terminalVisualizer) columnOf(panel *visualizerPanel, index int32) int {
+	return int(((int64(index)+1)*int64(visualizer.panelWidth) - 1) / int64(len(panel.data)))
+}
+
+// highlight marks the column showing the element at index, keeping the more prominent highlight if it is already marked
+func (visualizer *terminalVisualizer) highlight(panel *visualizerPanel, index int32, kind byte) {
+	var column int = visualizer.columnOf(panel, index)
+	if panel.highlights[column] < kind {
+		panel.highlights[column] = kind
+	}
+}
+
+func (visualizer *terminalVisualizer) observeComparison(algorithm int, ce ComparisonEvent) {
+	visualizer.mutex.Lock()
+	defer visualizer.mutex.Unlock()
+	var panel *visualizerPanel = visualizer.panelOf[algorithm]
+	visualizer.highlight(panel, ce.index[0], HIGHLIGHT_COMPARED)
+	visualizer.highlight(panel, ce.index[1], HIGHLIGHT_COMPARED)
+}
+
+func (visualizer *terminalVisualizer) observeSwap(algorithm int, se SwapEvent) {
+	visualizer.mutex.Lock()
+	defer visualizer.mutex.Unlock()
+	var panel *visualizerPanel = visualizer.panelOf[algorithm]
+	panel.data[se.index[0]] = se.value[1]
+	panel.data[se.index[1]] = se.value[0]
+	visualizer.highlight(panel, se.index[0], HIGHLIGHT_SWAPPED)
+	visualizer.highlight(panel, se.index[1], HIGHLIGHT_SWAPPED)
+}
+
+func (visualizer *terminalVisualizer) observeWrite(algorithm int, we WriteEvent) {
+	if we.toAuxiliary {
+		return // the auxiliary buffer is not drawn
+	}
+	visualizer.mutex.Lock()
+	defer visualizer.mutex.Unlock()
+	var panel *visualizerPanel = visualizer.panelOf[algorithm]
+	panel.data[we.index[0]] = we.value[1]
+	visualizer.highlight(panel, we.index[0], HIGHLIGHT_WRITTEN)
+}
+
+// columnHeight is the height of the bar for one column of a panel, in eighths of a terminal row
+func (visualizer *terminalVisualizer) columnHeight(panel *visualizerPanel, column int) int {
+	var first int = column * len(panel.data) / visualizer.panelWidth
+	var last int = (column + 1) * len(panel.data) / visualizer.panelWidth
+	if last <= first {
+		last = first + 1
+	}
+	var total int64 = 0
+	for pos := first; pos < last; pos = pos + 1 {
+		total = total + int64(panel.data[pos]) - int64(visualizer.lowestValue)
+	}
+	var maximumHeight int64 = int64(visualizer.barHeight) * 8
+	if visualizer.valueRange == 0 {
+		return int(maximumHeight)
+	}
+	// the lowest value still gets a sliver so that every element is visible
+	return int(1 + total*(maximumHeight-1)/(visualizer.valueRange*int64(last-first)))
+}
+
+func (visualizer *terminalVisualizer) draw() {
+	visualizer.mutex.Lock()
+	var heights [][]int = make([][]int, len(visualizer.panels))
+	var colors [][]string = make([][]string, len(visualizer.panels))
+	for p, panel := range visualizer.panels {
+		if len(panel.data) == 0 {
+			continue
+		}
+		heights[p] = make([]int, visualizer.panelWidth)
+		colors[p] = make([]string, visualizer.panelWidth)
+		for column := 0; column < visualizer.panelWidth; column = column + 1 {
+			heights[p][column] = visualizer.columnHeight(panel, column)
+			colors[p][column] = highlightColor[panel.highlights[column]]
+			panel.highlights[column] = HIGHLIGHT_NONE
+		}
+	}
+	visualizer.mutex.Unlock()
+	var frame strings.Builder
+	frame.WriteString(ansiCursorHome)
+	var currentColor string
+	for row := visualizer.barHeight - 1; row >= 0; row = row - 1 {
+		for p := range visualizer.panels {
+			if p > 0 {
+				frame.WriteString(strings.Repeat(" ", VISUALIZATION_PANEL_GAP))
+			}
+			for column := 0; column < visualizer.panelWidth; column = column + 1 {
+				if heights[p] == nil {
+					frame.WriteRune(' ')
+					continue
+				}
+				var fill int = heights[p][column] - row*8
+				if fill < 0 {
+					fill = 0
+				}
+				if fill > 8 {
+					fill = 8
+				}
+				if colors[p][column] != currentColor {
+					currentColor = colors[p][column]
+					frame.WriteString(currentColor)
+				}
+				frame.WriteRune(barBlocks[fill])
+			}
+		}
+		frame.WriteString(ansiClearToEndOfLine + "\n")
+	}
+	frame.WriteString(ansiResetColor)
+	for p, panel := range visualizer.panels {
+		if p > 0 {
+			frame.WriteString(strings.Repeat(" ", VISUALIZATION_PANEL_GAP))
+		}
+		var name string = shortAlgorithmName(panel.algorithm)
+		if len(name) > visualizer.panelWidth {
+			name = name[:visualizer.panelWidth]
+		}
+		fmt.Fprintf(&frame, "%-*s", visualizer.panelWidth, name)
+	}
+	frame.WriteString(ansiClearToEndOfLine + "\n")
+	fmt.Fprint(visualizer.output, frame.String())
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"io"
+	"testing"
+)
+
+func TestEachElementRaisesOnlyTheColumnWhichShowsIt(t *testing.T) {
+	for _, size := range []int{10, 1000, 1001} {
+		var startSlice []int32 = make([]int32, size)
+		startSlice[0] = 1000 // so that the lowest value is 0 and the range is 1000
+		var visualizer *terminalVisualizer = newTerminalVisualizer(io.Discard, []int{ALGORITHM_QUICK_SORT}, startSlice, 10, 37, 24)
+		var panel *visualizerPanel = visualizer.panels[0]
+		for index := 0; index < size; index = index + 1 {
+			for pos := range panel.data {
+				panel.data[pos] = 0
+			}
+			panel.data[index] = 1000
+			var shownIn int = visualizer.columnOf(panel, int32(index))
+			if shownIn < 0 || shownIn >= visualizer.panelWidth {
+				t.Fatalf("%d elements: element %d is shown in column %d of %d", size, index, shownIn, visualizer.panelWidth)
+			}
+			for column := 0; column < visualizer.panelWidth; column = column + 1 {
+				if raised := visualizer.columnHeight(panel, column) > 1; raised != (column == shownIn) {
+					t.Fatalf("%d elements: raising element %d, shown in column %d, left column %d raised=%v", size, index, shownIn, column, raised)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,424 @@
+package main
+
+/*
+Parallel Sorting Demo
+Copyright (C) 2020 Robert Sheridan
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sync"
+)
+
+/* Binary trace format (version 1)
+ * all integers are varints (encoding/binary) - signed values use the zig-zag Varint form, unsigned values Uvarint
+ * header:   the magic bytes "SORTTRACE", Uvarint version, Uvarint header length, then the header fields:
+ *           Varint seed, Uvarint size, Uvarint length + bytes of the distribution name, Varint inversions,
+ *           Uvarint algorithm count followed by a Uvarint id for each algorithm
+ * blocks:   Uvarint algorithm id, Uvarint payload length, then the payload holding consecutive events of that algorithm
+ * end:      a block with algorithm id 0 and no payload - a trace without it was truncated
+ * event:    one byte of kind (low 2 bits) and flags (firstWasLower, toAuxiliary), then Varint deltas of the sequence,
+ *           the first index, the second index (from the first index), Varint values,
+ *           and Varint deltas of knownToBeSortedCount and timestamp
+ * deltas are taken from the previous event of the same algorithm, so each algorithm forms its own stream
+ */
+
+const BINARY_TRACE_MAGIC string = "SORTTRACE"
+const BINARY_TRACE_VERSION uint64 = 1
+const BINARY_TRACE_BLOCK_SIZE int = 64 * 1024
+
+// the largest header and block a reader accepts, so a corrupt length cannot make it allocate without limit
+// a block is written once it reaches BINARY_TRACE_BLOCK_SIZE, so it can hold one event (a byte and seven varints) more
+const BINARY_TRACE_MAX_HEADER_SIZE uint64 = 64 * 1024
+const BINARY_TRACE_MAX_BLOCK_SIZE uint64 = uint64(BINARY_TRACE_BLOCK_SIZE) + 1 + 7*binary.MaxVarintLen64
+
+const TRACE_EVENT_COMPARISON byte = 1
+const TRACE_EVENT_SWAP byte = 2
+const TRACE_EVENT_WRITE byte = 3
+
+const binaryTraceKindMask byte = 0x03
+const binaryTraceFirstWasLowerFlag byte = 0x04
+const binaryTraceToAuxiliaryFlag byte = 0x08
+
+// traceHeader describes the data and algorithms of a recorded run
+type traceHeader struct {
+	data       dataSettings
+	algorithms []int
+}
+
+// traceEvent is a single recorded event of one algorithm - kind selects which of the event fields is set
+type traceEvent struct {
+	algorithm  int
+	kind       byte
+	comparison ComparisonEvent
+	swap       SwapEvent
+	write      WriteEvent
+}
+
+func (te traceEvent) sequence() int64 {
+	switch te.kind {
+	case TRACE_EVENT_COMPARISON:
+		return te.comparison.sequence
+	case TRACE_EVENT_SWAP:
+		return te.swap.sequence
+	}
+	return te.write.sequence
+}
+
+// binaryTraceDeltas holds the values of the previous event in an algorithm's stream
+type binaryTraceDeltas struct {
+	sequence             int64
+	index                int32
+	knownToBeSortedCount int32
+	timestamp            int64
+}
+
+func appendBinaryTraceEvent(buffer []byte, previous *binaryTraceDeltas, kindAndFlags byte, index [2]int32, value [2]int32, knownToBeSortedCount int32, sequence int64, timestamp int64) []byte {
+	buffer = append(buffer, kindAndFlags)
+	buffer = binary.AppendVarint(buffer, sequence-previous.sequence)
+	buffer = binary.AppendVarint(buffer, int64(index[0])-int64(previous.index))
+	buffer = binary.AppendVarint(buffer, int64(index[1])-int64(index[0]))
+	buffer = binary.AppendVarint(buffer, int64(value[0]))
+	buffer = binary.AppendVarint(buffer, int64(value[1]))
+	buffer = binary.AppendVarint(buffer, int64(knownToBeSortedCount)-int64(previous.knownToBeSortedCount))
+	buffer = binary.AppendVarint(buffer, timestamp-previous.timestamp)
+	*previous = binaryTraceDeltas{sequence, index[0], knownToBeSortedCount, timestamp}
+	return buffer
+}
+
+// binaryTraceWriter is an eventObserver which writes every event to a binary trace
+type binaryTraceWriter struct {
+	mutex      sync.Mutex
+	file       *os.File
+	writer     *bufio.Writer
+	algorithms []int
+	blocks     map[int][]byte // events not yet written, for each algorithm
+	previous   map[int]*binaryTraceDeltas
+	err        error // the first error encountered while writing
+}
+
+// newBinaryTraceWriter creates the trace file at path and writes the header
+func newBinaryTraceWriter(path string, header traceHeader) (*binaryTraceWriter, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	tw := new(binaryTraceWriter)
+	tw.file = file
+	tw.writer = bufio.NewWriter(file)
+	tw.algorithms = header.algorithms
+	tw.blocks = map[int][]byte{}
+	tw.previous = map[int]*binaryTraceDeltas{}
+	for _, algorithm := range header.algorithms {
+		tw.previous[algorithm] = new(binaryTraceDeltas)
+	}
+	tw.err = writeBinaryTraceHeader(tw.writer, header)
+	return tw, nil
+}
+
+func writeBinaryTraceHeader(w io.Writer, header traceHeader) error {
+	var fields []byte
+	fields = binary.AppendVarint(fields, header.data.seed)
+	fields = binary.AppendUvarint(fields, uint64(header.data.size))
+	fields = binary.AppendUvarint(fields, uint64(len(header.data.distribution)))
+	fields = append(fields, header.data.distribution...)
+	fields = binary.AppendVarint(fields, header.data.inversions)
+	fields = binary.AppendUvarint(fields, uint64(len(header.algorithms)))
+	for _, algorithm := range header.algorithms {
+		fields = binary.AppendUvarint(fields, uint64(algorithm))
+	}
+	var start []byte = []byte(BINARY_TRACE_MAGIC)
+	start = binary.AppendUvarint(start, BINARY_TRACE_VERSION)
+	start = binary.AppendUvarint(start, uint64(len(fields)))
+	if _, err := w.Write(start); err != nil {
+		return err
+	}
+	_, err := w.Write(fields)
+	return err
+}
+
+func (tw *binaryTraceWriter) writeBlock(algorithm int) {
+	var block []byte = tw.blocks[algorithm]
+	var prefix []byte
+	prefix = binary.AppendUvarint(prefix, uint64(algorithm))
+	prefix = binary.AppendUvarint(prefix, uint64(len(block)))
+	if _, err := tw.writer.Write(prefix); err != nil {
+		tw.err = err
+		return
+	}
+	if _, err := tw.writer.Write(block); err != nil {
+		tw.err = err
+		return
+	}
+	tw.blocks[algorithm] = block[:0]
+}
+
+func (tw *binaryTraceWriter) record(algorithm int, kindAndFlags byte, index [2]int32, value [2]int32, knownToBeSortedCount int32, sequence int64, timestamp int64) {
+	tw.mutex.Lock()
+	defer tw.mutex.Unlock()
+	if tw.err != nil {
+		return
+	}
+	previous, known := tw.previous[algorithm]
+	if !known {
+		tw.err = fmt.Errorf("algorithm %d is not in the trace header", algorithm)
+		return
+	}
+	tw.blocks[algorithm] = appendBinaryTraceEvent(tw.blocks[algorithm], previous, kindAndFlags, index, value, knownToBeSortedCount, sequence, timestamp)
+	if len(tw.blocks[algorithm]) >= BINARY_TRACE_BLOCK_SIZE {
+		tw.writeBlock(algorithm)
+	}
+}
+
+func (tw *binaryTraceWriter) observeComparison(algorithm int, ce ComparisonEvent) {
+	var kindAndFlags byte = TRACE_EVENT_COMPARISON
+	if ce.firstWasLower {
+		kindAndFlags = kindAndFlags | binaryTraceFirstWasLowerFlag
+	}
+	tw.record(algorithm, kindAndFlags, ce.index, ce.value, ce.knownToBeSortedCount, ce.sequence, ce.timestamp)
+}
+
+func (tw *binaryTraceWriter) observeSwap(algorithm int, se SwapEvent) {
+	tw.record(algorithm, TRACE_EVENT_SWAP, se.index, se.value, se.knownToBeSortedCount, se.sequence, se.timestamp)
+}
+
+func (tw *binaryTraceWriter) observeWrite(algorithm int, we WriteEvent) {
+	var kindAndFlags byte = TRACE_EVENT_WRITE
+	if we.toAuxiliary {
+		kindAndFlags = kindAndFlags | binaryTraceToAuxiliaryFlag
+	}
+	tw.record(algorithm, kindAndFlags, we.index, we.value, we.knownToBeSortedCount, we.sequence, we.timestamp)
+}
+
+// close writes any buffered events and the end of trace marker, and reports the first error encountered while writing
+func (tw *binaryTraceWriter) close() error {
+	tw.mutex.Lock()
+	defer tw.mutex.Unlock()
+	for _, algorithm := range tw.algorithms {
+		if tw.err == nil && len(tw.blocks[algorithm]) > 0 {
+			tw.writeBlock(algorithm)
+		}
+	}
+	if tw.err == nil {
+		_, tw.err = tw.writer.Write([]byte{0, 0})
+	}
+	if tw.err == nil {
+		tw.err = tw.writer.Flush()
+	}
+	if err := tw.file.Close(); tw.err == nil {
+		tw.err = err
+	}
+	return tw.err
+}
+
+var errTruncatedTrace = errors.New("trace ends without an end of trace marker")
+
+// binaryTraceReader iterates over the events of a binary trace in the order they were written
+// events of one algorithm come in the order they were observed, which may differ slightly from their sequence order
+type binaryTraceReader struct {
+	reader   *bufio.Reader
+	header   traceHeader
+	previous map[int]*binaryTraceDeltas
+	block    *bytes.Reader // the unread part of the current block
+	blockOf  int           // the algorithm of the current block
+	finished bool
+}
+
+// newBinaryTraceReader reads the trace header from r
+func newBinaryTraceReader(r io.Reader) (*binaryTraceReader, error) {
+	tr := new(binaryTraceReader)
+	tr.reader = bufio.NewReader(r)
+	var magic []byte = make([]byte, len(BINARY_TRACE_MAGIC))
+	if _, err := io.ReadFull(tr.reader, magic); err != nil || string(magic) != BINARY_TRACE_MAGIC {
+		return nil, errors.New("not a binary sorting trace")
+	}
+	version, err := binary.ReadUvarint(tr.reader)
+	if err != nil {
+		return nil, err
+	}
+	if version != BINARY_TRACE_VERSION {
+		return nil, fmt.Errorf("unsupported binary trace version %d", version)
+	}
+	headerLength, err := binary.ReadUvarint(tr.reader)
+	if err != nil {
+		return nil, err
+	}
+	if headerLength > BINARY_TRACE_MAX_HEADER_SIZE {
+		return nil, fmt.Errorf("invalid binary trace header: length %d is over the limit of %d bytes", headerLength, BINARY_TRACE_MAX_HEADER_SIZE)
+	}
+	var fields []byte = make([]byte, headerLength)
+	if _, err := io.ReadFull(tr.reader, fields); err != nil {
+		return nil, err
+	}
+	if tr.header, err = parseBinaryTraceHeader(bytes.NewReader(fields)); err != nil {
+		return nil, fmt.Errorf("invalid binary trace header: %v", err)
+	}
+	tr.previous = map[int]*binaryTraceDeltas{}
+	for _, algorithm := range tr.header.algorithms {
+		tr.previous[algorithm] = new(binaryTraceDeltas)
+	}
+	tr.block = bytes.NewReader(nil)
+	return tr, nil
+}
+
+func parseBinaryTraceHeader(fields *bytes.Reader) (traceHeader, error) {
+	var header traceHeader
+	var err error
+	if header.data.seed, err = binary.ReadVarint(fields); err != nil {
+		return header, err
+	}
+	size, err := binary.ReadUvarint(fields)
+	if err != nil {
+		return header, err
+	}
+	if size > math.MaxInt32 {
+		return header, fmt.Errorf("size %d is over the limit of %d", size, math.MaxInt32)
+	}
+	header.data.size = int32(size)
+	nameLength, err := binary.ReadUvarint(fields)
+	if err != nil {
+		return header, err
+	}
+	if nameLength > uint64(fields.Len()) {
+		return header, io.ErrUnexpectedEOF
+	}
+	var name []byte = make([]byte, nameLength)
+	_, _ = fields.Read(name)
+	header.data.distribution = string(name)
+	if header.data.inversions, err = binary.ReadVarint(fields); err != nil {
+		return header, err
+	}
+	count, err := binary.ReadUvarint(fields)
+	if err != nil {
+		return header, err
+	}
+	var i uint64
+	for i = 0; i < count; i = i + 1 {
+		algorithm, err := binary.ReadUvarint(fields)
+		if err != nil {
+			return header, err
+		}
+		header.algorithms = append(header.algorithms, int(algorithm))
+	}
+	if err := checkDataSettings(header.data); err != nil {
+		return header, err
+	}
+	return header, nil
+}
+
+// next returns the next event of the trace, or io.EOF after the last event
+func (tr *binaryTraceReader) next() (traceEvent, error) {
+	for tr.block.Len() == 0 {
+		if tr.finished {
+			return traceEvent{}, io.EOF
+		}
+		if err := tr.readBlock(); err != nil {
+			return traceEvent{}, err
+		}
+	}
+	event, err := tr.readEvent()
+	if err != nil {
+		return traceEvent{}, fmt.Errorf("invalid event in binary trace: %v", err)
+	}
+	return event, nil
+}
+
+func (tr *binaryTraceReader) readBlock() error {
+	algorithm, err := binary.ReadUvarint(tr.reader)
+	if err != nil {
+		return errTruncatedTrace
+	}
+	length, err := binary.ReadUvarint(tr.reader)
+	if err != nil {
+		return errTruncatedTrace
+	}
+	if algorithm == 0 {
+		tr.finished = true
+		return nil
+	}
+	if _, known := tr.previous[int(algorithm)]; !known {
+		return fmt.Errorf("block for algorithm %d which is not in the trace header", algorithm)
+	}
+	if length > BINARY_TRACE_MAX_BLOCK_SIZE {
+		return fmt.Errorf("invalid binary trace block: length %d is over the limit of %d bytes", length, BINARY_TRACE_MAX_BLOCK_SIZE)
+	}
+	var block []byte = make([]byte, length)
+	if _, err := io.ReadFull(tr.reader, block); err != nil {
+		return errTruncatedTrace
+	}
+	tr.block = bytes.NewReader(block)
+	tr.blockOf = int(algorithm)
+	return nil
+}
+
+func (tr *binaryTraceReader) readEvent() (traceEvent, error) {
+	var previous *binaryTraceDeltas = tr.previous[tr.blockOf]
+	kindAndFlags, err := tr.block.ReadByte()
+	if err != nil {
+		return traceEvent{}, err
+	}
+	var deltas [7]int64
+	for field := range deltas {
+		if deltas[field], err = binary.ReadVarint(tr.block); err != nil {
+			return traceEvent{}, err
+		}
+	}
+	var sequence int64 = previous.sequence + deltas[0]
+	var index0 int32 = int32(int64(previous.index) + deltas[1])
+	var index [2]int32 = [2]int32{index0, int32(int64(index0) + deltas[2])}
+	var value [2]int32 = [2]int32{int32(deltas[3]), int32(deltas[4])}
+	var knownToBeSortedCount int32 = int32(int64(previous.knownToBeSortedCount) + deltas[5])
+	var timestamp int64 = previous.timestamp + deltas[6]
+	*previous = binaryTraceDeltas{sequence, index0, knownToBeSortedCount, timestamp}
+	var event traceEvent = traceEvent{algorithm: tr.blockOf, kind: kindAndFlags & binaryTraceKindMask}
+	switch event.kind {
+	case TRACE_EVENT_COMPARISON:
+		event.comparison = ComparisonEvent{index, value, kindAndFlags&binaryTraceFirstWasLowerFlag != 0, knownToBeSortedCount, sequence, timestamp}
+	case TRACE_EVENT_SWAP:
+		event.swap = SwapEvent{index, value, knownToBeSortedCount, sequence, timestamp}
+	case TRACE_EVENT_WRITE:
+		event.write = WriteEvent{index, value, kindAndFlags&binaryTraceToAuxiliaryFlag != 0, knownToBeSortedCount, sequence, timestamp}
+	default:
+		return traceEvent{}, fmt.Errorf("unknown event kind %d", event.kind)
+	}
+	return event, nil
+}
+
+// readBinaryTrace reads a whole binary trace
+func readBinaryTrace(r io.Reader) (traceHeader, []traceEvent, error) {
+	tr, err := newBinaryTraceReader(r)
+	if err != nil {
+		return traceHeader{}, nil, err
+	}
+	var events []traceEvent
+	for {
+		event, err := tr.next()
+		if err == io.EOF {
+			return tr.header, events, nil
+		}
+		if err != nil {
+			return tr.header, nil, err
+		}
+		events = append(events, event)
+	}
+}
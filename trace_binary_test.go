@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBinaryTraceRoundTrip(t *testing.T) {
+	var header traceHeader = traceHeader{dataSettings{DISTRIBUTION_RANDOM, 6, -12345, 3}, []int{ALGORITHM_QUICK_SORT, ALGORITHM_MERGE_SORT}}
+	var written []traceEvent = []traceEvent{
+		{algorithm: ALGORITHM_QUICK_SORT, kind: TRACE_EVENT_COMPARISON, comparison: ComparisonEvent{[2]int32{4, 1}, [2]int32{7, -3}, false, 0, 1, 1000}},
+		{algorithm: ALGORITHM_MERGE_SORT, kind: TRACE_EVENT_WRITE, write: WriteEvent{[2]int32{0, 5}, [2]int32{0, 9}, true, 0, 1, 1001}},
+		{algorithm: ALGORITHM_QUICK_SORT, kind: TRACE_EVENT_SWAP, swap: SwapEvent{[2]int32{0, 5}, [2]int32{2, 1}, 1, 2, 990}},
+		{algorithm: ALGORITHM_QUICK_SORT, kind: TRACE_EVENT_COMPARISON, comparison: ComparisonEvent{[2]int32{2, 3}, [2]int32{1, 2}, true, 1, 3, 1200}},
+		{algorithm: ALGORITHM_MERGE_SORT, kind: TRACE_EVENT_WRITE, write: WriteEvent{[2]int32{5, 0}, [2]int32{4, 9}, false, 6, 2, 1300}},
+	}
+	var path string = filepath.Join(t.TempDir(), "trace.bin")
+	tw, err := newBinaryTraceWriter(path, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, event := range written {
+		switch event.kind {
+		case TRACE_EVENT_COMPARISON:
+			tw.observeComparison(event.algorithm, event.comparison)
+		case TRACE_EVENT_SWAP:
+			tw.observeSwap(event.algorithm, event.swap)
+		case TRACE_EVENT_WRITE:
+			tw.observeWrite(event.algorithm, event.write)
+		}
+	}
+	if err := tw.close(); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	tr, err := newBinaryTraceReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.header.data != header.data || len(tr.header.algorithms) != 2 || tr.header.algorithms[1] != ALGORITHM_MERGE_SORT {
+		t.Errorf("header read back as %+v, expected %+v", tr.header, header)
+	}
+	// events come back grouped into one block per algorithm, each in the order written
+	var read = map[int][]traceEvent{}
+	for {
+		event, err := tr.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		read[event.algorithm] = append(read[event.algorithm], event)
+	}
+	var expected = map[int][]traceEvent{}
+	for _, event := range written {
+		expected[event.algorithm] = append(expected[event.algorithm], event)
+	}
+	for algorithm, events := range expected {
+		if len(read[algorithm]) != len(events) {
+			t.Fatalf("read %d events for %s, expected %d", len(read[algorithm]), algorithmName[algorithm], len(events))
+		}
+		for i := range events {
+			if read[algorithm][i] != events[i] {
+				t.Errorf("event %d of %s read back as %+v, expected %+v", i, algorithmName[algorithm], read[algorithm][i], events[i])
+			}
+		}
+	}
+}
+
+func TestBinaryTraceReaderRejectsOversizedLengths(t *testing.T) {
+	var header []byte = append([]byte(BINARY_TRACE_MAGIC), byte(BINARY_TRACE_VERSION))
+	if _, err := newBinaryTraceReader(bytes.NewReader(binary.AppendUvarint(header, 1<<40))); err == nil {
+		t.Error("a header length of a terabyte was accepted")
+	}
+	var trace bytes.Buffer
+	if err := writeBinaryTraceHeader(&trace, traceHeader{dataSettings{DISTRIBUTION_RANDOM, 6, 1, 0}, []int{ALGORITHM_QUICK_SORT}}); err != nil {
+		t.Fatal(err)
+	}
+	var block []byte = binary.AppendUvarint(nil, uint64(ALGORITHM_QUICK_SORT))
+	trace.Write(binary.AppendUvarint(block, 1<<40))
+	tr, err := newBinaryTraceReader(&trace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tr.next(); err == nil || err == errTruncatedTrace {
+		t.Errorf("a block length of a terabyte was not reported as invalid, got %v", err)
+	}
+}
+
+func TestBinaryTraceHeaderRejectsDataItCannotGenerate(t *testing.T) {
+	var headerFields = func(size uint64, distribution string) []byte {
+		var fields []byte = binary.AppendVarint(nil, 1)
+		fields = binary.AppendUvarint(fields, size)
+		fields = binary.AppendUvarint(fields, uint64(len(distribution)))
+		fields = append(fields, distribution...)
+		fields = binary.AppendVarint(fields, 0)
+		return binary.AppendUvarint(fields, 0)
+	}
+	if _, err := parseBinaryTraceHeader(bytes.NewReader(headerFields(6, DISTRIBUTION_RANDOM))); err != nil {
+		t.Fatalf("a valid header was rejected: %v", err)
+	}
+	if _, err := parseBinaryTraceHeader(bytes.NewReader(headerFields(1<<32, DISTRIBUTION_RANDOM))); err == nil {
+		t.Error("a size of 1<<32, which would be truncated to 0, was accepted")
+	}
+	if _, err := parseBinaryTraceHeader(bytes.NewReader(headerFields(6, "no-such-distribution"))); err == nil {
+		t.Error("an unknown distribution was accepted")
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+/*
+Parallel Sorting Demo
+Copyright (C) 2020 Robert Sheridan
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+const TRACE_KIND_RUN string = "run"
+const TRACE_KIND_COMPARISON string = "comparison"
+const TRACE_KIND_SWAP string = "swap"
+const TRACE_KIND_WRITE string = "write"
+
+// traceRunRecord is the first line of a JSON Lines trace, describing the data every algorithm started from
+type traceRunRecord struct {
+	Kind         string   `json:"kind"`
+	Distribution string   `json:"distribution"`
+	Size         int32    `json:"size"`
+	Seed         int64    `json:"seed"`
+	Inversions   int64    `json:"inversions"`
+	Algorithms   []string `json:"algorithms"`
+}
+
+func runRecord(header traceHeader) traceRunRecord {
+	var names []string = make([]string, 0, len(header.algorithms))
+	for _, algorithm := range header.algorithms {
+		names = append(names, algorithmName[algorithm])
+	}
+	return traceRunRecord{TRACE_KIND_RUN, header.data.distribution, header.data.size, header.data.seed, header.data.inversions, names}
+}
+
+// traceEventRecord is a line of a JSON Lines trace describing a single event
+type traceEventRecord struct {
+	Kind                 string   `json:"kind"`
+	Algorithm            string   `json:"algorithm"`
+	Sequence             int64    `json:"sequence"`
+	Index                [2]int32 `json:"index"`
+	Value                [2]int32 `json:"value"`
+	FirstWasLower        *bool    `json:"firstWasLower,omitempty"` // comparisons only
+	ToAuxiliary          *bool    `json:"toAuxiliary,omitempty"`   // writes only
+	KnownToBeSortedCount int32    `json:"knownToBeSortedCount"`
+	Timestamp            int64    `json:"timestamp"`
+}
+
+// jsonlTraceRecorder is an eventObserver which writes every event as a line of JSON
+// lines from different algorithms (and from the channels of one algorithm) are interleaved - order them by sequence
+type jsonlTraceRecorder struct {
+	mutex   sync.Mutex
+	file    *os.File
+	writer  *bufio.Writer
+	encoder *json.Encoder
+	err     error // the first error encountered while writing
+}
+
+// newJSONLTraceRecorder creates the trace file at path and writes the run record describing the data
+func newJSONLTraceRecorder(path string, header traceHeader) (*jsonlTraceRecorder, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	recorder := new(jsonlTraceRecorder)
+	recorder.file = file
+	recorder.writer = bufio.NewWriter(file)
+	recorder.encoder = json.NewEncoder(recorder.writer)
+	recorder.encode(runRecord(header))
+	return recorder, nil
+}
+
+func (recorder *jsonlTraceRecorder) encode(record interface{}) {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	if recorder.err != nil {
+		return
+	}
+	recorder.err = recorder.encoder.Encode(record)
+}
+
+func comparisonRecord(algorithm int, ce ComparisonEvent) traceEventRecord {
+	var firstWasLower bool = ce.firstWasLower
+	return traceEventRecord{TRACE_KIND_COMPARISON, algorithmName[algorithm], ce.sequence, ce.index, ce.value, &firstWasLower, nil, ce.knownToBeSortedCount, ce.timestamp}
+}
+
+func swapRecord(algorithm int, se SwapEvent) traceEventRecord {
+	return traceEventRecord{TRACE_KIND_SWAP, algorithmName[algorithm], se.sequence, se.index, se.value, nil, nil, se.knownToBeSortedCount, se.timestamp}
+}
+
+func writeRecord(algorithm int, we WriteEvent) traceEventRecord {
+	var toAuxiliary bool = we.toAuxiliary
+	return traceEventRecord{TRACE_KIND_WRITE, algorithmName[algorithm], we.sequence, we.index, we.value, nil, &toAuxiliary, we.knownToBeSortedCount, we.timestamp}
+}
+
+func (recorder *jsonlTraceRecorder) observeComparison(algorithm int, ce ComparisonEvent) {
+	recorder.encode(comparisonRecord(algorithm, ce))
+}
+
+func (recorder *jsonlTraceRecorder) observeSwap(algorithm int, se SwapEvent) {
+	recorder.encode(swapRecord(algorithm, se))
+}
+
+func (recorder *jsonlTraceRecorder) observeWrite(algorithm int, we WriteEvent) {
+	recorder.encode(writeRecord(algorithm, we))
+}
+
+// close flushes the trace to its file and reports the first error encountered while recording
+func (recorder *jsonlTraceRecorder) close() error {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	if recorder.err == nil {
+		recorder.err = recorder.writer.Flush()
+	}
+	if err := recorder.file.Close(); recorder.err == nil {
+		recorder.err = err
+	}
+	return recorder.err
+}
+
+// readJSONLTrace reads a whole JSON Lines trace written by jsonlTraceRecorder
+func readJSONLTrace(r io.Reader) (traceHeader, []traceEvent, error) {
+	var header traceHeader
+	var events []traceEvent
+	var decoder *json.Decoder = json.NewDecoder(r)
+	var run traceRunRecord
+	if err := decoder.Decode(&run); err != nil || run.Kind != TRACE_KIND_RUN {
+		return header, nil, fmt.Errorf("trace does not begin with a %s record", TRACE_KIND_RUN)
+	}
+	header.data = dataSettings{run.Distribution, run.Size, run.Seed, run.Inversions}
+	if err := checkDataSettings(header.data); err != nil {
+		return header, nil, fmt.Errorf("trace describes data which cannot be generated: %v", err)
+	}
+	for _, name := range run.Algorithms {
+		algorithm, found := findRegisteredAlgorithm(name)
+		if !found {
+			return header, nil, fmt.Errorf("trace names unknown algorithm %q", name)
+		}
+		header.algorithms = append(header.algorithms, algorithm)
+	}
+	for line := 2; decoder.More(); line = line + 1 {
+		var record traceEventRecord
+		if err := decoder.Decode(&record); err != nil {
+			return header, nil, fmt.Errorf("line %d of trace: %v", line, err)
+		}
+		algorithm, found := findRegisteredAlgorithm(record.Algorithm)
+		if !found {
+			return header, nil, fmt.Errorf("line %d of trace names unknown algorithm %q", line, record.Algorithm)
+		}
+		var event traceEvent = traceEvent{algorithm: algorithm}
+		switch record.Kind {
+		case TRACE_KIND_COMPARISON:
+			event.kind = TRACE_EVENT_COMPARISON
+			event.comparison = ComparisonEvent{record.Index, record.Value, record.FirstWasLower != nil && *record.FirstWasLower, record.KnownToBeSortedCount, record.Sequence, record.Timestamp}
+		case TRACE_KIND_SWAP:
+			event.kind = TRACE_EVENT_SWAP
+			event.swap = SwapEvent{record.Index, record.Value, record.KnownToBeSortedCount, record.Sequence, record.Timestamp}
+		case TRACE_KIND_WRITE:
+			event.kind = TRACE_EVENT_WRITE
+			event.write = WriteEvent{record.Index, record.Value, record.ToAuxiliary != nil && *record.ToAuxiliary, record.KnownToBeSortedCount, record.Sequence, record.Timestamp}
+		default:
+			return header, nil, fmt.Errorf("line %d of trace has unknown kind %q", line, record.Kind)
+		}
+		events = append(events, event)
+	}
+	return header, events, nil
+}
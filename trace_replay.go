@@ -0,0 +1,232 @@
+package main
+
+/*
+Parallel Sorting Demo
+Copyright (C) 2020 Robert Sheridan
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// readTraceFile reads a binary or JSON Lines trace, telling the formats apart by the binary trace magic bytes
+func readTraceFile(path string) (traceHeader, []traceEvent, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return traceHeader{}, nil, err
+	}
+	defer file.Close()
+	var reader *bufio.Reader = bufio.NewReader(file)
+	magic, _ := reader.Peek(len(BINARY_TRACE_MAGIC))
+	if string(magic) == BINARY_TRACE_MAGIC {
+		return readBinaryTrace(reader)
+	}
+	return readJSONLTrace(reader)
+}
+
+// eventsOfAlgorithm selects the events of one algorithm and puts them in the order the routine emitted them
+func eventsOfAlgorithm(events []traceEvent, algorithm int) []traceEvent {
+	var selected []traceEvent = make([]traceEvent, 0)
+	for _, event := range events {
+		if event.algorithm == algorithm {
+			selected = append(selected, event)
+		}
+	}
+	sort.SliceStable(selected, func(i, j int) bool {
+		return selected[i].sequence() < selected[j].sequence()
+	})
+	return selected
+}
+
+// replayMismatchError reports an event whose recorded values differ from the reconstructed array
+type replayMismatchError struct {
+	algorithm   int
+	eventNumber int // the position of the event in the replay, starting at 1
+	event       traceEvent
+	description string
+}
+
+func (e *replayMismatchError) Error() string {
+	return fmt.Sprintf("%s event %d (sequence %d): %s", algorithmName[e.algorithm], e.eventNumber, e.event.sequence(), e.description)
+}
+
+// traceReplay rebuilds the history of one routine's data by applying its recorded events to the starting data
+type traceReplay struct {
+	algorithm  int
+	startSlice []int32
+	events     []traceEvent
+	data       []int32
+	auxiliary  []int32
+	position   int // the number of events applied so far
+}
+
+// newTraceReplay prepares to replay the events of algorithm, which may be mixed with events of other algorithms, from startSlice
+func newTraceReplay(startSlice []int32, events []traceEvent, algorithm int) *traceReplay {
+	replay := new(traceReplay)
+	replay.algorithm = algorithm
+	replay.startSlice = startSlice
+	replay.events = eventsOfAlgorithm(events, algorithm)
+	replay.rewind()
+	return replay
+}
+
+// rewind returns the replay to the starting data, before any event
+func (replay *traceReplay) rewind() {
+	replay.data = make([]int32, len(replay.startSlice))
+	_ = copy(replay.data, replay.startSlice)
+	replay.auxiliary = make([]int32, len(replay.startSlice))
+	replay.position = 0
+}
+
+func (replay *traceReplay) eventCount() int {
+	return len(replay.events)
+}
+
+func (replay *traceReplay) mismatch(event traceEvent, format string, a ...interface{}) error {
+	return &replayMismatchError{replay.algorithm, replay.position + 1, event, fmt.Sprintf(format, a...)}
+}
+
+func (replay *traceReplay) inRange(buffer []int32, index int32) bool {
+	return index >= 0 && int(index) < len(buffer)
+}
+
+// checkValues verifies that buffers hold the recorded values at the recorded indexes
+func (replay *traceReplay) checkValues(event traceEvent, index [2]int32, value [2]int32, buffers [2][]int32) error {
+	for k := 0; k < 2; k = k + 1 {
+		if !replay.inRange(buffers[k], index[k]) {
+			return replay.mismatch(event, "index %d is outside the array of %d elements", index[k], len(buffers[k]))
+		}
+		if buffers[k][index[k]] != value[k] {
+			return replay.mismatch(event, "recorded value %d at index %d but the array holds %d", value[k], index[k], buffers[k][index[k]])
+		}
+	}
+	return nil
+}
+
+// step applies the next event, first checking that its recorded values match the reconstructed array
+// a mismatched event is not applied, so the replay stays at the last consistent state
+func (replay *traceReplay) step() error {
+	if replay.position >= len(replay.events) {
+		return fmt.Errorf("%s replay is already at the last of %d events", algorithmName[replay.algorithm], len(replay.events))
+	}
+	var event traceEvent = replay.events[replay.position]
+	switch event.kind {
+	case TRACE_EVENT_COMPARISON:
+		var ce ComparisonEvent = event.comparison
+		if err := replay.checkValues(event, ce.index, ce.value, [2][]int32{replay.data, replay.data}); err != nil {
+			return err
+		}
+		if ce.firstWasLower != (ce.value[0] < ce.value[1]) {
+			return replay.mismatch(event, "recorded firstWasLower %t when comparing %d with %d", ce.firstWasLower, ce.value[0], ce.value[1])
+		}
+	case TRACE_EVENT_SWAP:
+		var se SwapEvent = event.swap
+		if err := replay.checkValues(event, se.index, se.value, [2][]int32{replay.data, replay.data}); err != nil {
+			return err
+		}
+		replay.data[se.index[0]] = se.value[1]
+		replay.data[se.index[1]] = se.value[0]
+	case TRACE_EVENT_WRITE:
+		var we WriteEvent = event.write
+		var destination, source []int32 = replay.data, replay.auxiliary
+		if we.toAuxiliary {
+			destination, source = replay.auxiliary, replay.data
+		}
+		if err := replay.checkValues(event, we.index, we.value, [2][]int32{destination, source}); err != nil {
+			return err
+		}
+		destination[we.index[0]] = we.value[1]
+	}
+	replay.position = replay.position + 1
+	return nil
+}
+
+// seek replays up to (and including) eventNumber, counting from 1, and returns a copy of the data at that moment
+// seeking to event 0 returns the starting data, and seeking backwards replays again from the start
+func (replay *traceReplay) seek(eventNumber int) ([]int32, error) {
+	if eventNumber < 0 || eventNumber > len(replay.events) {
+		return nil, fmt.Errorf("event %d is outside the %d events of the %s replay", eventNumber, len(replay.events), algorithmName[replay.algorithm])
+	}
+	if eventNumber < replay.position {
+		replay.rewind()
+	}
+	for replay.position < eventNumber {
+		if err := replay.step(); err != nil {
+			return nil, err
+		}
+	}
+	return replay.snapshot(), nil
+}
+
+// snapshot returns a copy of the reconstructed data at the current position
+func (replay *traceReplay) snapshot() []int32 {
+	var data []int32 = make([]int32, len(replay.data))
+	_ = copy(data, replay.data)
+	return data
+}
+
+// eventsPerFrameWithin chooses how many events each frame of an animation coalesces so that the longest event stream of algorithms fits within frameBudget frames
+func eventsPerFrameWithin(events []traceEvent, algorithms []int, frameBudget int) int {
+	var longest int = 0
+	for _, algorithm := range algorithms {
+		if count := len(eventsOfAlgorithm(events, algorithm)); count > longest {
+			longest = count
+		}
+	}
+	var eventsPerFrame int = (longest + frameBudget - 1) / frameBudget
+	if eventsPerFrame < 1 {
+		eventsPerFrame = 1
+	}
+	return eventsPerFrame
+}
+
+// finished tells whether every event has been applied
+func (replay *traceReplay) finished() bool {
+	return replay.position >= len(replay.events)
+}
+
+// advance applies up to count more events, returning the indexes of the latest comparison among them or -1 if there was none
+func (replay *traceReplay) advance(count int) ([2]int32, error) {
+	var compared [2]int32 = [2]int32{-1, -1}
+	for ; count > 0 && !replay.finished(); count = count - 1 {
+		var event traceEvent = replay.events[replay.position]
+		if err := replay.step(); err != nil {
+			return compared, err
+		}
+		if event.kind == TRACE_EVENT_COMPARISON {
+			compared = event.comparison.index
+		}
+	}
+	return compared, nil
+}
+
+// replayFrames replays the whole trace from the start, calling visit with the data before any event and then after every eventsPerFrame events
+// (and after the last event), along with the indexes of the latest comparison in the frame or -1 if the frame had none
+func (replay *traceReplay) replayFrames(eventsPerFrame int, visit func(frame int, data []int32, compared [2]int32)) error {
+	replay.rewind()
+	visit(0, replay.data, [2]int32{-1, -1})
+	for frame := 1; !replay.finished(); frame = frame + 1 {
+		compared, err := replay.advance(eventsPerFrame)
+		if err != nil {
+			return err
+		}
+		visit(frame, replay.data, compared)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+// raceOptionsForTest describes a quiet race of algorithms over size randomly ordered elements generated from seed
+func raceOptionsForTest(algorithms []int, size int32, seed int64) runOptions {
+	return runOptions{algorithms: algorithms, data: dataSettings{DISTRIBUTION_RANDOM, size, seed, 0}, output: OUTPUT_FORMAT_CSV, reportPeriodStep: 1, quiet: true}
+}
+
+// raceForTest races algorithms over size randomly ordered elements generated from seed, passing every event to observer,
+// and returns the data they started from along with their results
+func raceForTest(t *testing.T, algorithms []int, size int32, seed int64, observer eventObserver) ([]int32, []*algorithmResult) {
+	t.Helper()
+	var options runOptions = raceOptionsForTest(algorithms, size, seed)
+	var startSlice []int32 = makeDataArray(options.data)
+	return startSlice, runSortRace(context.Background(), startSlice, options, observer)
+}
+
+func TestTraceReplayReconstructsEveryRoutine(t *testing.T) {
+	var collector *traceEventCollector = new(traceEventCollector)
+	startSlice, results := raceForTest(t, defaultAlgorithms(), 60, 7, collector)
+	var events []traceEvent = collector.collectedEvents()
+	for _, result := range results {
+		var replay *traceReplay = newTraceReplay(startSlice, events, result.algorithm)
+		if int64(replay.eventCount()) != result.eventCount() {
+			t.Errorf("%s replay has %d events, expected %d", algorithmName[result.algorithm], replay.eventCount(), result.eventCount())
+		}
+		final, err := replay.seek(replay.eventCount())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for pos := range final {
+			if final[pos] != result.routine.getData()[pos] {
+				t.Fatalf("%s replay differs from the sorted data at position %d", algorithmName[result.algorithm], pos)
+			}
+		}
+		start, err := replay.seek(0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for pos := range start {
+			if start[pos] != startSlice[pos] {
+				t.Fatalf("%s replay seeking back to the start differs at position %d", algorithmName[result.algorithm], pos)
+			}
+		}
+	}
+}
+
+func TestTraceReplayDetectsMismatchedValues(t *testing.T) {
+	var startSlice []int32 = []int32{3, 1, 2}
+	var events []traceEvent = []traceEvent{
+		{algorithm: ALGORITHM_BUBBLE_SORT, kind: TRACE_EVENT_SWAP, swap: SwapEvent{[2]int32{0, 1}, [2]int32{3, 1}, 0, 1, 0}},
+		{algorithm: ALGORITHM_BUBBLE_SORT, kind: TRACE_EVENT_COMPARISON, comparison: ComparisonEvent{[2]int32{1, 2}, [2]int32{2, 3}, true, 0, 2, 0}},
+	}
+	var replay *traceReplay = newTraceReplay(startSlice, events, ALGORITHM_BUBBLE_SORT)
+	if err := replay.step(); err != nil {
+		t.Fatal(err)
+	}
+	err := replay.step()
+	mismatch, isMismatch := err.(*replayMismatchError)
+	if !isMismatch || mismatch.eventNumber != 2 {
+		t.Fatalf("expected a mismatch at event 2, got %v", err)
+	}
+}
+
+func TestJSONLTraceReaderRejectsDataItCannotGenerate(t *testing.T) {
+	for _, data := range []dataSettings{{"no-such-distribution", 6, 1, 0}, {DISTRIBUTION_RANDOM, -5, 1, 0}, {DISTRIBUTION_K_INVERSIONS, 6, 1, -1}} {
+		var trace bytes.Buffer
+		if err := json.NewEncoder(&trace).Encode(runRecord(traceHeader{data, []int{ALGORITHM_QUICK_SORT}})); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := readJSONLTrace(&trace); err == nil {
+			t.Errorf("trace of %+v was accepted", data)
+		}
+	}
+}
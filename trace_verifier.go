@@ -0,0 +1,222 @@
+package main
+
+/*
+Parallel Sorting Demo
+Copyright (C) 2020 Robert Sheridan
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+const MAX_REPORTED_INCONSISTENCIES int = 20
+
+// traceInconsistency describes an event which contradicts the data or the earlier events of its routine
+type traceInconsistency struct {
+	algorithm   int
+	eventNumber int // the position of the event in the routine's event stream, starting at 1
+	sequence    int64
+	description string
+}
+
+func (inconsistency traceInconsistency) String() string {
+	return fmt.Sprintf("%s event %d (sequence %d): %s", algorithmName[inconsistency.algorithm], inconsistency.eventNumber, inconsistency.sequence, inconsistency.description)
+}
+
+// traceVerifier walks the event stream of one routine, reconstructing its data and checking each event against it
+type traceVerifier struct {
+	algorithm       int
+	sortedRegion    int
+	events          []traceEvent
+	data            []int32
+	auxiliary       []int32
+	settledBefore   []int32 // for each event, the number of positions which are never modified again from that event on
+	inconsistencies []traceInconsistency
+}
+
+func eventKnownToBeSortedCount(event traceEvent) int32 {
+	switch event.kind {
+	case TRACE_EVENT_COMPARISON:
+		return event.comparison.knownToBeSortedCount
+	case TRACE_EVENT_SWAP:
+		return event.swap.knownToBeSortedCount
+	}
+	return event.write.knownToBeSortedCount
+}
+
+// modifiedDataPositions lists the positions of the data array which an event changes
+func modifiedDataPositions(event traceEvent) []int32 {
+	switch event.kind {
+	case TRACE_EVENT_SWAP:
+		return event.swap.index[:]
+	case TRACE_EVENT_WRITE:
+		if !event.write.toAuxiliary {
+			return event.write.index[:1]
+		}
+	}
+	return nil
+}
+
+// verifyTrace checks the events of algorithm (which may be mixed with events of other algorithms) against startSlice
+func verifyTrace(startSlice []int32, events []traceEvent, algorithm int) []traceInconsistency {
+	verifier := new(traceVerifier)
+	verifier.algorithm = algorithm
+	verifier.sortedRegion = SORTED_REGION_SCATTERED
+	if registered, exists := sortAlgorithmRegistry[algorithm]; exists {
+		verifier.sortedRegion = registered.sortedRegion
+	}
+	verifier.events = eventsOfAlgorithm(events, algorithm)
+	verifier.data = make([]int32, len(startSlice))
+	_ = copy(verifier.data, startSlice)
+	verifier.auxiliary = make([]int32, len(startSlice))
+	verifier.findSettledPositions()
+	verifier.run()
+	return verifier.inconsistencies
+}
+
+// findSettledPositions counts, before each event, the positions which no later event modifies
+func (verifier *traceVerifier) findSettledPositions() {
+	var lastModifiedBy []int = make([]int, len(verifier.data))
+	for pos := range lastModifiedBy {
+		lastModifiedBy[pos] = -1
+	}
+	for eventIndex, event := range verifier.events {
+		for _, pos := range modifiedDataPositions(event) {
+			if pos >= 0 && int(pos) < len(lastModifiedBy) {
+				lastModifiedBy[pos] = eventIndex
+			}
+		}
+	}
+	sort.Ints(lastModifiedBy)
+	verifier.settledBefore = make([]int32, len(verifier.events))
+	var settled int32 = 0
+	for eventIndex := range verifier.events {
+		for int(settled) < len(lastModifiedBy) && lastModifiedBy[settled] < eventIndex {
+			settled = settled + 1
+		}
+		verifier.settledBefore[eventIndex] = settled
+	}
+}
+
+func (verifier *traceVerifier) report(eventIndex int, format string, a ...interface{}) {
+	var event traceEvent = verifier.events[eventIndex]
+	verifier.inconsistencies = append(verifier.inconsistencies, traceInconsistency{verifier.algorithm, eventIndex + 1, event.sequence(), fmt.Sprintf(format, a...)})
+}
+
+// valueAt returns the reconstructed value at index, reporting an index outside the buffer
+func (verifier *traceVerifier) valueAt(eventIndex int, buffer []int32, index int32, recorded int32) (int32, bool) {
+	if index < 0 || int(index) >= len(buffer) {
+		verifier.report(eventIndex, "index %d is outside the array of %d elements", index, len(buffer))
+		return 0, false
+	}
+	if buffer[index] != recorded {
+		verifier.report(eventIndex, "recorded value %d at index %d but the array holds %d", recorded, index, buffer[index])
+	}
+	return buffer[index], true
+}
+
+// inSortedRegion tells whether pos lies among the positions claimed to be sorted
+func (verifier *traceVerifier) inSortedRegion(pos int32, knownToBeSortedCount int32) bool {
+	switch verifier.sortedRegion {
+	case SORTED_REGION_HEAD:
+		return pos < knownToBeSortedCount
+	case SORTED_REGION_TAIL:
+		return pos >= int32(len(verifier.data))-knownToBeSortedCount
+	}
+	return false
+}
+
+func (verifier *traceVerifier) run() {
+	var previousCount int32 = 0
+	var overclaiming bool = false
+	for eventIndex, event := range verifier.events {
+		var count int32 = eventKnownToBeSortedCount(event)
+		if count < previousCount {
+			verifier.report(eventIndex, "knownToBeSortedCount decreased from %d to %d", previousCount, count)
+		}
+		previousCount = count
+		// report when an algorithm starts claiming more final elements than there are, not at every following event
+		// (elements in an ordered head region are not claimed to be final)
+		var settled int32 = verifier.settledBefore[eventIndex]
+		var claimsFinal bool = verifier.sortedRegion != SORTED_REGION_ORDERED_HEAD
+		if claimsFinal && count > settled && !overclaiming {
+			verifier.report(eventIndex, "knownToBeSortedCount claims %d elements are in their final position but only %d are never moved again", count, settled)
+		}
+		overclaiming = claimsFinal && count > settled
+		for _, pos := range modifiedDataPositions(event) {
+			if verifier.inSortedRegion(pos, count) {
+				verifier.report(eventIndex, "moves the element at index %d inside the known sorted region of %d elements", pos, count)
+			}
+		}
+		verifier.apply(eventIndex, event)
+	}
+}
+
+// apply checks the recorded values of an event and applies it to the reconstructed data
+func (verifier *traceVerifier) apply(eventIndex int, event traceEvent) {
+	switch event.kind {
+	case TRACE_EVENT_COMPARISON:
+		var ce ComparisonEvent = event.comparison
+		first, firstValid := verifier.valueAt(eventIndex, verifier.data, ce.index[0], ce.value[0])
+		second, secondValid := verifier.valueAt(eventIndex, verifier.data, ce.index[1], ce.value[1])
+		if firstValid && secondValid && ce.firstWasLower != (first < second) {
+			verifier.report(eventIndex, "recorded firstWasLower %t when comparing %d with %d", ce.firstWasLower, first, second)
+		}
+	case TRACE_EVENT_SWAP:
+		var se SwapEvent = event.swap
+		first, firstValid := verifier.valueAt(eventIndex, verifier.data, se.index[0], se.value[0])
+		second, secondValid := verifier.valueAt(eventIndex, verifier.data, se.index[1], se.value[1])
+		if firstValid && secondValid {
+			verifier.data[se.index[0]] = second
+			verifier.data[se.index[1]] = first
+		}
+	case TRACE_EVENT_WRITE:
+		var we WriteEvent = event.write
+		var destination, source []int32 = verifier.data, verifier.auxiliary
+		if we.toAuxiliary {
+			destination, source = verifier.auxiliary, verifier.data
+		}
+		_, destinationValid := verifier.valueAt(eventIndex, destination, we.index[0], we.value[0])
+		value, sourceValid := verifier.valueAt(eventIndex, source, we.index[1], we.value[1])
+		if destinationValid && sourceValid {
+			destination[we.index[0]] = value
+		}
+	}
+}
+
+// reportTraceVerification verifies every algorithm and prints what was found, returning the number of inconsistencies
+func reportTraceVerification(w io.Writer, startSlice []int32, events []traceEvent, algorithms []int) int {
+	var total int = 0
+	for _, algorithm := range algorithms {
+		var inconsistencies []traceInconsistency = verifyTrace(startSlice, events, algorithm)
+		total = total + len(inconsistencies)
+		if len(inconsistencies) == 0 {
+			fmt.Fprintf(w, "%s event stream is consistent\n", algorithmName[algorithm])
+			continue
+		}
+		fmt.Fprintf(w, "%s event stream has %d inconsistencies\n", algorithmName[algorithm], len(inconsistencies))
+		for index, inconsistency := range inconsistencies {
+			if index == MAX_REPORTED_INCONSISTENCIES {
+				fmt.Fprintf(w, "  ... and %d more\n", len(inconsistencies)-index)
+				break
+			}
+			fmt.Fprintln(w, "  "+inconsistency.String())
+		}
+	}
+	return total
+}
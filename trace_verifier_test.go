@@ -0,0 +1,46 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTraceVerifierFindsLostQuickSortProgress(t *testing.T) {
+	var collector *traceEventCollector = new(traceEventCollector)
+	startSlice, _ := raceForTest(t, []int{ALGORITHM_HEAP_SORT, ALGORITHM_QUICK_SORT}, 200, 11, collector)
+	var events []traceEvent = collector.collectedEvents()
+	if inconsistencies := verifyTrace(startSlice, events, ALGORITHM_HEAP_SORT); len(inconsistencies) > 0 {
+		t.Errorf("heap sort reported as inconsistent: %v", inconsistencies[0])
+	}
+	// quick sort's insertionSort has a value receiver, so its knownToBeSortedCount increments are lost on return
+	var decreased bool = false
+	for _, inconsistency := range verifyTrace(startSlice, events, ALGORITHM_QUICK_SORT) {
+		if strings.Contains(inconsistency.description, "decreased") {
+			decreased = true
+		}
+	}
+	if !decreased {
+		t.Errorf("quick sort knownToBeSortedCount was not reported to decrease")
+	}
+}
+
+func TestTraceVerifierFindsMovesInsideSortedRegion(t *testing.T) {
+	var startSlice []int32 = []int32{0, 2, 1}
+	var events []traceEvent = []traceEvent{
+		{algorithm: ALGORITHM_SELECTION_SORT, kind: TRACE_EVENT_COMPARISON, comparison: ComparisonEvent{[2]int32{2, 1}, [2]int32{1, 2}, true, 2, 1, 0}},
+		{algorithm: ALGORITHM_SELECTION_SORT, kind: TRACE_EVENT_SWAP, swap: SwapEvent{[2]int32{0, 2}, [2]int32{0, 1}, 2, 2, 0}},
+	}
+	var inconsistencies []traceInconsistency = verifyTrace(startSlice, events, ALGORITHM_SELECTION_SORT)
+	var found = map[string]bool{}
+	for _, inconsistency := range inconsistencies {
+		if strings.Contains(inconsistency.description, "final position") {
+			found["final"] = true
+		}
+		if strings.Contains(inconsistency.description, "inside the known sorted region") {
+			found["region"] = true
+		}
+	}
+	if !found["final"] || !found["region"] {
+		t.Errorf("expected both an early finality claim and a move inside the sorted region, got %v", inconsistencies)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+/*
+Parallel Sorting Demo
+Copyright (C) 2020 Robert Sheridan
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import "context"
+
+const NO_TREE_NODE int32 = -1
+
+// TreeSortRoutine - sorts by inserting every element into a binary search tree and reading the tree back in order
+type TreeSortRoutine struct {
+	sortRoutineBase
+	auxiliary   []int32 // tree node values, node n holds the element originally at position n
+	lowerChild  []int32 // index of the subtree of smaller elements for each node
+	higherChild []int32 // index of the subtree of larger or equal elements for each node
+}
+
+// NewTreeSortRoutine factory
+func NewTreeSortRoutine(startSlice []int32) *TreeSortRoutine {
+	tsr := new(TreeSortRoutine)
+	tsr.sortRoutineBase = newSortRoutineBase(startSlice)
+	tsr.auxiliary = make([]int32, tsr.dataSize)
+	tsr.lowerChild = make([]int32, tsr.dataSize)
+	tsr.higherChild = make([]int32, tsr.dataSize)
+	var pos int32
+	for pos = 0; pos < tsr.dataSize; pos = pos + 1 {
+		tsr.lowerChild[pos] = NO_TREE_NODE
+		tsr.higherChild[pos] = NO_TREE_NODE
+	}
+	return tsr
+}
+
+func init() {
+	registerSortAlgorithm(ALGORITHM_TREE_SORT, registeredSortAlgorithm{
+		factory:           func(startSlice []int32) SortRoutine { return NewTreeSortRoutine(startSlice) },
+		includedByDefault: true,
+		sortedRegion:      SORTED_REGION_HEAD,
+	})
+}
+
+// store the element at pos as a new tree node and link it below the node found by descending from the root
+func (tsr TreeSortRoutine) insert(pos int32) {
+	tsr.copyElementToAuxiliary(tsr.auxiliary, pos, pos)
+	if pos == 0 {
+		return // the first element is the root
+	}
+	var node int32 = 0
+	for true {
+		// the data array is not modified while the tree is built, so node values can be compared in place
+		if tsr.compareElementsAt(pos, node) {
+			if tsr.lowerChild[node] == NO_TREE_NODE {
+				tsr.lowerChild[node] = pos
+				return
+			}
+			node = tsr.lowerChild[node]
+		} else {
+			if tsr.higherChild[node] == NO_TREE_NODE {
+				tsr.higherChild[node] = pos
+				return
+			}
+			node = tsr.higherChild[node]
+		}
+	}
+}
+
+/* Tree Sort
+ * insert each element into an (unbalanced) binary search tree, descending to the lower subtree when the
+ * element is smaller than the node and to the higher subtree otherwise
+ * walk the tree in order and write each node value back into the list
+ * elements reach their final position as they are written back
+ */
+func (tsr TreeSortRoutine) run(ctx context.Context) {
+	var pos int32
+	for pos = 0; pos < tsr.dataSize; pos = pos + 1 {
+		if ctx.Err() != nil {
+			return
+		}
+		tsr.insert(pos)
+	}
+	var pathFromRoot []int32 = make([]int32, 0)
+	var node int32 = NO_TREE_NODE
+	if tsr.dataSize > 0 {
+		node = 0
+	}
+	pos = 0
+	for node != NO_TREE_NODE || len(pathFromRoot) > 0 {
+		if ctx.Err() != nil {
+			return
+		}
+		for node != NO_TREE_NODE {
+			pathFromRoot = append(pathFromRoot, node)
+			node = tsr.lowerChild[node]
+		}
+		// pop the next node in order
+		node = pathFromRoot[len(pathFromRoot)-1]
+		pathFromRoot = pathFromRoot[:len(pathFromRoot)-1]
+		tsr.copyElementFromAuxiliary(tsr.auxiliary, pos, node)
+		tsr.knownToBeSortedCount = tsr.knownToBeSortedCount + 1
+		pos = pos + 1
+		node = tsr.higherChild[node]
+	}
+	tsr.sortingRoutineComplete()
+}